@@ -0,0 +1,297 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package batch encodes many QR codes at once.
+//
+// ReadCSV and ReadJSONL read records, each giving an ID, a payload,
+// and optionally a level and a version. Run encodes the records using
+// a bounded pool of goroutines and writes an image named for each
+// record's ID, such as id.png or id.svg, along with a manifest
+// describing every record, to a directory (Dir) or a zip archive (Zip).
+//
+// A record that cannot be encoded does not stop the batch:
+// Run reports the error in the record's Result and in the manifest
+// and continues with the next record.
+package batch // import "rsc.io/qr/batch"
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	"rsc.io/qr"
+)
+
+// A Record describes a single code to encode.
+type Record struct {
+	Line    int    // line number in the input, for error messages
+	ID      string // file name for the code, without extension
+	Payload string // text to encode
+	Level   string // error correction level L, M, Q, or H; "" means Options.Level
+	Version int    // QR version to use; 0 means the smallest that fits
+
+	err error // error reading the record
+}
+
+// A Result describes the outcome of encoding a Record.
+type Result struct {
+	Record
+	Files []string // names of the files written
+	Info  *qr.Info // encoding details, if Err is nil
+	Err   error    // error encoding the record
+}
+
+// Options controls how Run encodes and writes records.
+// A nil *Options means the default options.
+type Options struct {
+	Level   qr.Level // level for records that do not give one
+	Scale   int      // image pixels per module; 0 means 8
+	Border  int      // quiet zone width in modules; 0 means 4, negative means none
+	Formats []string // image formats: "png", "svg", or both; nil means png
+	Workers int      // number of goroutines encoding; 0 means runtime.GOMAXPROCS(0)
+
+	// MinVersion and MaxVersion bound the QR version
+	// for records that do not give one, as in qr.Options.
+	MinVersion int
+	MaxVersion int
+
+	// Mask is the QR mask to use, from 0 to 7, or qr.AutoMask,
+	// as in qr.Options.
+	Mask int
+
+	// Manifest is the name of the manifest file.
+	// The empty string means "manifest.json".
+	Manifest string
+}
+
+// A Writer stores the files written by Run.
+// Run calls WriteFile from a single goroutine.
+type Writer interface {
+	WriteFile(name string, data []byte) error
+}
+
+// Run encodes recs and writes the images and the manifest to w.
+// It returns a Result for each record, in the same order.
+// Run returns an error only if writing to w fails,
+// in which case it stops early.
+func Run(recs []Record, w Writer, opt *Options) ([]Result, error) {
+	var o Options
+	if opt != nil {
+		o = *opt
+	}
+	if o.Scale <= 0 {
+		o.Scale = 8
+	}
+	if o.Border == 0 {
+		o.Border = 4
+	} else if o.Border < 0 {
+		o.Border = 0
+	}
+	if len(o.Formats) == 0 {
+		o.Formats = []string{"png"}
+	}
+	if o.Workers <= 0 {
+		o.Workers = runtime.GOMAXPROCS(0)
+	}
+	if o.Manifest == "" {
+		o.Manifest = "manifest.json"
+	}
+	for _, f := range o.Formats {
+		if f != "png" && f != "svg" {
+			return nil, fmt.Errorf("batch: unknown format %q", f)
+		}
+	}
+	if o.MinVersion < 0 || o.MaxVersion < 0 || o.MinVersion > 40 || o.MaxVersion > 40 || o.MaxVersion > 0 && o.MinVersion > o.MaxVersion {
+		return nil, fmt.Errorf("batch: invalid version range %d to %d", o.MinVersion, o.MaxVersion)
+	}
+	if o.Mask < qr.AutoMask || o.Mask > 7 {
+		return nil, fmt.Errorf("batch: invalid mask %d", o.Mask)
+	}
+
+	results := make([]Result, len(recs))
+	skip := make([]bool, len(recs))
+	seen := make(map[string]int)
+	for i, rec := range recs {
+		err := rec.err
+		if err == nil {
+			err = checkID(rec.ID)
+		}
+		if err == nil {
+			if j, ok := seen[rec.ID]; ok {
+				err = fmt.Errorf("duplicate id %q (also line %d)", rec.ID, recs[j].Line)
+			} else {
+				seen[rec.ID] = i
+			}
+		}
+		results[i] = Result{Record: rec, Err: err}
+		skip[i] = err != nil
+	}
+
+	// Encode in parallel, but write in record order,
+	// so that the output does not depend on scheduling.
+	type encoded struct {
+		i     int
+		info  *qr.Info
+		files map[string][]byte
+		err   error
+	}
+	work := make(chan int)
+	done := make(chan encoded, o.Workers)
+	stop := make(chan struct{})
+	go func() {
+		defer close(work)
+		for i := range recs {
+			if skip[i] {
+				continue
+			}
+			select {
+			case work <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for n := 0; n < o.Workers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				info, files, err := encode(&recs[i], &o)
+				done <- encoded{i, info, files, err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	pending := make(map[int]encoded)
+	next := 0
+	var werr error
+	for e := range done {
+		if werr != nil {
+			continue
+		}
+		results[e.i].Info, results[e.i].Err = e.info, e.err
+		pending[e.i] = e
+		for ; next < len(results); next++ {
+			if skip[next] {
+				continue
+			}
+			e, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			if e.err != nil {
+				continue
+			}
+			if werr = writeFiles(w, &results[next], e.files, o.Formats); werr != nil {
+				close(stop)
+				break
+			}
+		}
+	}
+	if werr != nil {
+		return results, werr
+	}
+
+	if err := w.WriteFile(o.Manifest, manifest(results)); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// checkID checks that id is usable as a file name.
+func checkID(id string) error {
+	switch {
+	case id == "":
+		return fmt.Errorf("missing id")
+	case id == "." || id == ".." || strings.ContainsAny(id, "/\\:\x00"):
+		return fmt.Errorf("invalid id %q", id)
+	}
+	return nil
+}
+
+// encode encodes the record, returning the encoding details
+// and the image data for each format.
+func encode(rec *Record, o *Options) (*qr.Info, map[string][]byte, error) {
+	level := o.Level
+	if rec.Level != "" {
+		i := strings.Index("LMQH", strings.ToUpper(rec.Level))
+		if len(rec.Level) != 1 || i < 0 {
+			return nil, nil, fmt.Errorf("invalid level %q", rec.Level)
+		}
+		level = qr.Level(i)
+	}
+	if rec.Version < 0 || rec.Version > 40 {
+		return nil, nil, fmt.Errorf("invalid version %d", rec.Version)
+	}
+	qo := &qr.Options{MinVersion: o.MinVersion, MaxVersion: o.MaxVersion, Mask: o.Mask}
+	if rec.Version != 0 {
+		qo.MinVersion, qo.MaxVersion = rec.Version, rec.Version
+	}
+	c, info, err := qr.EncodeOptions(rec.Payload, level, qo)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.Scale = o.Scale
+	files := make(map[string][]byte)
+	for _, f := range o.Formats {
+		switch f {
+		case "png":
+			files[f] = c.PNGBorder(o.Border)
+		case "svg":
+			files[f] = c.SVGBorder(o.Border)
+		}
+	}
+	return info, files, nil
+}
+
+func writeFiles(w Writer, r *Result, files map[string][]byte, formats []string) error {
+	for _, f := range formats {
+		name := r.ID + "." + f
+		if err := w.WriteFile(name, files[f]); err != nil {
+			return err
+		}
+		r.Files = append(r.Files, name)
+	}
+	return nil
+}
+
+// manifest returns the JSON manifest for results.
+func manifest(results []Result) []byte {
+	type entry struct {
+		Line     int      `json:"line,omitempty"`
+		ID       string   `json:"id"`
+		Files    []string `json:"files,omitempty"`
+		Version  int      `json:"version,omitempty"`
+		Level    string   `json:"level,omitempty"`
+		Mask     *int     `json:"mask,omitempty"`
+		Mode     string   `json:"mode,omitempty"`
+		Bits     int      `json:"bits,omitempty"`
+		Capacity int      `json:"capacity,omitempty"`
+		Error    string   `json:"error,omitempty"`
+	}
+	list := make([]entry, len(results))
+	for i, r := range results {
+		e := &list[i]
+		e.Line, e.ID, e.Files = r.Line, r.ID, r.Files
+		if r.Err != nil {
+			e.Error = r.Err.Error()
+			continue
+		}
+		e.Version, e.Level, e.Mask = r.Info.Version, "LMQH"[r.Info.Level:r.Info.Level+1], &r.Info.Mask
+		e.Mode, e.Bits, e.Capacity = r.Info.Mode, r.Info.Bits, r.Info.Capacity
+	}
+	data, err := json.MarshalIndent(list, "", "\t")
+	if err != nil {
+		panic(err) // cannot happen
+	}
+	return append(data, '\n')
+}
@@ -0,0 +1,273 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package batch
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rsc.io/qr"
+	"rsc.io/qr/decode"
+)
+
+const csvInput = `id,payload,level,version
+a,hello,,
+b,"with, comma",H,
+c,0123456789,m,5
+d,too long for version 1 at level H,H,1
+,missing id,,
+a,duplicate,,
+e,bad version,,x
+f,bad level,Z,
+g/h,bad id,,
+`
+
+func TestReadCSV(t *testing.T) {
+	recs, err := ReadCSV(strings.NewReader(csvInput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 9 {
+		t.Fatalf("ReadCSV: %d records, want 9", len(recs))
+	}
+	r := recs[2]
+	if r.Line != 4 || r.ID != "c" || r.Payload != "0123456789" || r.Level != "m" || r.Version != 5 {
+		t.Errorf("ReadCSV record 2 = %+v", r)
+	}
+	if recs[1].Payload != "with, comma" {
+		t.Errorf("ReadCSV record 1 payload = %q", recs[1].Payload)
+	}
+	if recs[6].err == nil {
+		t.Errorf("ReadCSV accepted version x")
+	}
+
+	if _, err := ReadCSV(strings.NewReader("name,text\nx,y\n")); err == nil {
+		t.Errorf("ReadCSV accepted header without id and payload")
+	}
+}
+
+func TestReadJSONL(t *testing.T) {
+	recs, err := ReadJSONL(strings.NewReader(`{"id":"a","payload":"hello"}
+
+{"id":"b","payload":"x","level":"Q","version":3}
+not json
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 3 {
+		t.Fatalf("ReadJSONL: %d records, want 3", len(recs))
+	}
+	if r := recs[1]; r.Line != 3 || r.ID != "b" || r.Level != "Q" || r.Version != 3 {
+		t.Errorf("ReadJSONL record 1 = %+v", r)
+	}
+	if recs[2].err == nil || recs[2].Line != 4 {
+		t.Errorf("ReadJSONL record 2 = %+v, want error at line 4", recs[2])
+	}
+}
+
+// A mapWriter is a Writer that records files in memory.
+type mapWriter struct {
+	files map[string][]byte
+	order []string
+	fail  string // file name to fail on
+}
+
+func (m *mapWriter) WriteFile(name string, data []byte) error {
+	if name == m.fail {
+		return errors.New("write failed")
+	}
+	if m.files == nil {
+		m.files = make(map[string][]byte)
+	}
+	m.files[name] = data
+	m.order = append(m.order, name)
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	recs, err := ReadCSV(strings.NewReader(csvInput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w mapWriter
+	results, err := Run(recs, &w, &Options{Level: qr.M, Formats: []string{"png", "svg"}, Workers: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failed []int
+	for i, r := range results {
+		if r.Err != nil {
+			failed = append(failed, i)
+		}
+	}
+	if fmt.Sprint(failed) != "[3 4 5 6 7 8]" {
+		t.Errorf("failed records %v, want [3 4 5 6 7 8]", failed)
+		for _, r := range results {
+			t.Logf("%s: %v", r.ID, r.Err)
+		}
+	}
+	want := "a.png a.svg b.png b.svg c.png c.svg manifest.json"
+	if got := strings.Join(w.order, " "); got != want {
+		t.Errorf("files written: %s, want %s", got, want)
+	}
+
+	// Check the images and per-row settings.
+	for _, tt := range []struct {
+		id      string
+		text    string
+		level   qr.Level
+		version int
+	}{
+		{"a", "hello", qr.M, 1},
+		{"b", "with, comma", qr.H, 0},
+		{"c", "0123456789", qr.M, 5},
+	} {
+		m, err := png.Decode(bytes.NewReader(w.files[tt.id+".png"]))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.id, err)
+		}
+		r, err := decode.Decode(m)
+		if err != nil {
+			t.Fatalf("%s: decode: %v", tt.id, err)
+		}
+		if r.Text != tt.text || r.Level != tt.level || tt.version != 0 && r.Version != tt.version {
+			t.Errorf("%s: decoded %q level %v version %d", tt.id, r.Text, r.Level, r.Version)
+		}
+	}
+
+	var man []struct {
+		ID       string
+		Files    []string
+		Version  int
+		Mask     *int
+		Capacity int
+		Error    string
+	}
+	if err := json.Unmarshal(w.files["manifest.json"], &man); err != nil {
+		t.Fatal(err)
+	}
+	if len(man) != len(recs) {
+		t.Fatalf("manifest has %d entries, want %d", len(man), len(recs))
+	}
+	// A version 5 code at level M holds 86 data bytes.
+	if m := man[2]; m.ID != "c" || m.Version != 5 || m.Mask == nil || m.Capacity != 86*8 || len(m.Files) != 2 {
+		t.Errorf("manifest entry 2 = %+v", m)
+	}
+	for _, i := range failed {
+		if man[i].Error == "" || man[i].Mask != nil {
+			t.Errorf("manifest entry %d = %+v, want error", i, man[i])
+		}
+	}
+}
+
+func TestRunWriteError(t *testing.T) {
+	var recs []Record
+	for i := 0; i < 100; i++ {
+		recs = append(recs, Record{ID: fmt.Sprint(i), Payload: fmt.Sprint("payload ", i)})
+	}
+	w := &mapWriter{fail: "50.png"}
+	_, err := Run(recs, w, &Options{Workers: 4})
+	if err == nil {
+		t.Fatalf("Run succeeded despite write error")
+	}
+	if len(w.order) != 50 {
+		t.Errorf("wrote %d files before error, want 50", len(w.order))
+	}
+}
+
+func TestZipAndDir(t *testing.T) {
+	recs := []Record{{ID: "x", Payload: "one"}, {ID: "y", Payload: "two"}}
+
+	var buf bytes.Buffer
+	z := Zip(&buf)
+	if _, err := Run(recs, z, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if got := strings.Join(names, " "); got != "x.png y.png manifest.json" {
+		t.Errorf("zip files: %s", got)
+	}
+
+	dir := filepath.Join(t.TempDir(), "out")
+	if _, err := Run(recs, Dir(dir), &Options{Formats: []string{"svg"}, Manifest: "list.json"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"x.svg", "y.svg", "list.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestRunOptions(t *testing.T) {
+	recs := []Record{
+		{Line: 2, ID: "x", Payload: "one"},
+		{Line: 3, ID: "x", Payload: "two"},
+		{Line: 4, ID: "x", Payload: "three"},
+		{Line: 5, ID: "y", Payload: "four", Version: 2},
+	}
+	var w mapWriter
+	results, err := Run(recs, &w, &Options{Scale: 1, Border: -1, MinVersion: 3, MaxVersion: 4, Mask: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every duplicate names the first record with the ID.
+	for _, r := range results[1:3] {
+		if r.Err == nil || !strings.Contains(r.Err.Error(), "also line 2") {
+			t.Errorf("line %d: error %v, want duplicate of line 2", r.Line, r.Err)
+		}
+	}
+	for _, tt := range []struct {
+		i, version int
+	}{
+		{0, 3},
+		{3, 2},
+	} {
+		r := results[tt.i]
+		if r.Err != nil {
+			t.Fatalf("%s: %v", r.ID, r.Err)
+		}
+		if r.Info.Version != tt.version || r.Info.Mask != 5 {
+			t.Errorf("%s: version %d mask %d, want version %d mask 5", r.ID, r.Info.Version, r.Info.Mask, tt.version)
+		}
+		m, err := png.Decode(bytes.NewReader(w.files[r.ID+".png"]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// No border at scale 1: one pixel per module.
+		if d := m.Bounds().Dx(); d != 17+4*tt.version {
+			t.Errorf("%s: image width %d, want %d", r.ID, d, 17+4*tt.version)
+		}
+	}
+
+	for _, opt := range []*Options{
+		{MinVersion: 5, MaxVersion: 4},
+		{MaxVersion: 41},
+		{Mask: 8},
+	} {
+		if _, err := Run(recs, &mapWriter{}, opt); err == nil {
+			t.Errorf("Run with %+v succeeded", opt)
+		}
+	}
+}
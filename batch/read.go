@@ -0,0 +1,104 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package batch
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadCSV reads records from CSV data. The first line is a header
+// naming the columns: id and payload are required, and level and
+// version are optional. Other columns are ignored.
+//
+// ReadCSV returns an error only for malformed CSV or a bad header.
+// An invalid level or version makes a record that Run
+// reports as failed.
+func ReadCSV(r io.Reader) ([]Record, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	col := map[string]int{"id": -1, "payload": -1, "level": -1, "version": -1}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if j, ok := col[name]; ok && j < 0 {
+			col[name] = i
+		}
+	}
+	if col["id"] < 0 || col["payload"] < 0 {
+		return nil, fmt.Errorf("csv header must name id and payload columns")
+	}
+
+	var recs []Record
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		get := func(name string) string {
+			if i := col[name]; 0 <= i && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+		rec := Record{Line: line, ID: get("id"), Payload: get("payload"), Level: strings.TrimSpace(get("level"))}
+		if v := strings.TrimSpace(get("version")); v != "" {
+			rec.Version, err = strconv.Atoi(v)
+			if err != nil {
+				rec.err = fmt.Errorf("invalid version %q", v)
+			}
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+// ReadJSONL reads records from JSON Lines data: one JSON object per
+// line, with string fields id, payload, and level and an integer
+// field version. Blank lines are ignored.
+//
+// ReadJSONL returns an error only if reading fails.
+// A line that is not a valid record makes a record that Run
+// reports as failed.
+func ReadJSONL(r io.Reader) ([]Record, error) {
+	var recs []Record
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" {
+			continue
+		}
+		var j struct {
+			ID      string `json:"id"`
+			Payload string `json:"payload"`
+			Level   string `json:"level"`
+			Version int    `json:"version"`
+		}
+		rec := Record{Line: line}
+		if err := json.Unmarshal([]byte(text), &j); err != nil {
+			rec.err = err
+		} else {
+			rec.ID, rec.Payload, rec.Level, rec.Version = j.ID, j.Payload, j.Level, j.Version
+		}
+		recs = append(recs, rec)
+	}
+	return recs, s.Err()
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package batch
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Dir returns a Writer that writes files into the directory dir,
+// creating it if needed.
+func Dir(dir string) Writer {
+	return dirWriter(dir)
+}
+
+type dirWriter string
+
+func (d dirWriter) WriteFile(name string, data []byte) error {
+	if err := os.MkdirAll(string(d), 0777); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(string(d), name), data, 0666)
+}
+
+// A ZipWriter is a Writer that writes files into a zip archive.
+type ZipWriter struct {
+	zw *zip.Writer
+}
+
+// Zip returns a ZipWriter writing a zip archive to w.
+// The caller must call Close to finish the archive.
+func Zip(w io.Writer) *ZipWriter {
+	return &ZipWriter{zip.NewWriter(w)}
+}
+
+// WriteFile adds a file to the archive.
+func (z *ZipWriter) WriteFile(name string, data []byte) error {
+	// PNG data is already compressed; store it as is.
+	method := zip.Deflate
+	if filepath.Ext(name) == ".png" {
+		method = zip.Store
+	}
+	f, err := z.zw.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// Close finishes writing the archive.
+// It does not close the underlying writer.
+func (z *ZipWriter) Close() error {
+	return z.zw.Close()
+}
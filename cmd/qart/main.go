@@ -0,0 +1,299 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Qart creates artistic QR codes.
+//
+// Usage:
+//
+//	qart -url URL -img file [options]
+//	qart -text text -img file [options]
+//	qart -url URL -banner text [options]
+//
+// Qart draws a QR code encoding URL or text that resembles the image in file,
+// which may be a PNG, JPEG, or GIF. The algorithms are described at
+// https://research.swtch.com/qart. It is the command-line equivalent
+// of the QArt Coder at https://research.swtch.com/qr/draw/.
+//
+// With -banner, qart draws text in a built-in 5×7 font instead of
+// an image and reports the number of text modules it could not draw.
+//
+// A URL is followed by "#" and digits that qart chooses to draw the image.
+// Text is followed by free padding that readers ignore, so it can be
+// anything: plain text, a Wi-Fi configuration, and so on.
+//
+// The options are:
+//
+//	-query name
+//		put the digits in the query parameter name instead of the fragment
+//	-sep s
+//		follow text by s and free bytes instead of free padding
+//	-alpha n
+//		leave pixels with alpha below n free (default 0: only fully transparent)
+//	-weight file
+//		prioritize parts of the image by the brightness of
+//		the corresponding pixels in the image in file
+//	-o file
+//		write the code to file (default qart.png)
+//	-control file
+//		write a PNG showing which pixels were controlled to file
+//	-svg file
+//		write the code as SVG to file
+//	-halftone file
+//		write a halftone PNG to file, in which only the center of each
+//		module carries its bit and the rest reproduces the image
+//	-report file
+//		write a heat map PNG to file showing which modules match
+//		the image, and print a table of statistics by module role
+//	-color file
+//		write a color PNG to file, with hues taken from the image
+//	-threshold n, -margin n
+//		with -color, make dark modules' luminance at most n-m and
+//		light modules' luminance at least n+m (default 128 and 48)
+//	-version n
+//		use QR version n, from 1 to 40 (default 6)
+//	-level l
+//		use QR error correction level l: L, M, Q, or H (default L)
+//	-mask n
+//		use QR mask n (default 2)
+//	-rotate n
+//		rotate the code n quarter turns (default 0)
+//	-dx n, -dy n
+//		offset the code n pixels within the image (default 4)
+//	-size n
+//		enlarge the image n pixels beyond the code size
+//	-scale n
+//		draw each QR pixel as an n×n square
+//		(default 8, or less for versions 12 and up)
+//	-dither
+//		dither instead of thresholding the image
+//	-rand
+//		pick the pixels to control randomly
+//	-data
+//		control only data bits, not check bits
+//	-seed n
+//		seed the random choices with n, to reproduce an earlier code
+//	-search
+//		try every mask and rotation and keep the one that
+//		best resembles the image
+//	-range n
+//		with -search, also try offsets within n pixels of -dx and -dy
+//		and sizes within n of -size
+//
+// In -search mode, qart prints the fidelity scores of the best
+// candidates, the fraction of image pixels (weighted by contrast)
+// that the code draws correctly, along with the flags that
+// reproduce each one.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"log"
+	"os"
+	"strings"
+
+	"rsc.io/qr"
+	"rsc.io/qr/qart"
+)
+
+var (
+	url     = flag.String("url", "", "encode `URL`")
+	text    = flag.String("text", "", "encode `text`")
+	query   = flag.String("query", "", "put digits in query parameter `name`")
+	sep     = flag.String("sep", "", "follow text by separator `s` and free bytes")
+	imgFile = flag.String("img", "", "draw the image in `file`")
+	banner  = flag.String("banner", "", "draw `text` instead of an image")
+	alpha   = flag.Int("alpha", 0, "alpha `threshold` for don't-care pixels")
+	weight  = flag.String("weight", "", "read weight map from `file`")
+	out     = flag.String("o", "qart.png", "write code to `file`")
+	control = flag.String("control", "", "write control PNG to `file`")
+	svgFile = flag.String("svg", "", "write SVG to `file`")
+	htFile  = flag.String("halftone", "", "write halftone PNG to `file`")
+	clrFile = flag.String("color", "", "write color PNG to `file`")
+	report  = flag.String("report", "", "write heat map PNG to `file` and print statistics")
+	thresh  = flag.Int("threshold", 128, "with -color, luminance threshold")
+	margin  = flag.Int("margin", 48, "with -color, luminance margin")
+	version = flag.Int("version", 6, "QR version")
+	level   = flag.String("level", "L", "QR error correction `level` (L, M, Q, H)")
+	mask    = flag.Int("mask", 2, "QR mask")
+	rotate  = flag.Int("rotate", 0, "rotate code `n` quarter turns")
+	dx      = flag.Int("dx", 4, "horizontal offset of code within image")
+	dy      = flag.Int("dy", 4, "vertical offset of code within image")
+	size    = flag.Int("size", 0, "enlarge image `n` pixels beyond code size")
+	scale   = flag.Int("scale", 0, "image pixels per QR pixel (0 for automatic)")
+	dither  = flag.Bool("dither", false, "dither instead of thresholding")
+	random  = flag.Bool("rand", false, "pick pixels randomly")
+	data    = flag.Bool("data", false, "control only data bits")
+	seed    = flag.Int64("seed", 0, "random `seed` (0 for time-based)")
+	search  = flag.Bool("search", false, "search all masks and rotations")
+	srange  = flag.Int("range", 0, "with -search, also search offsets and sizes within `n`")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: qart -url URL -img file [options]\n")
+	fmt.Fprintf(os.Stderr, "       qart -text text -img file [options]\n")
+	fmt.Fprintf(os.Stderr, "       qart -url URL -banner text [options]\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("qart: ")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 0 || (*url == "") == (*text == "") || (*imgFile == "") == (*banner == "") {
+		usage()
+	}
+
+	var payload *qart.Payload
+	switch {
+	case *text != "" && *sep != "":
+		payload = qart.Filler(*text, *sep)
+	case *text != "":
+		payload = qart.Padding(*text)
+	case *query != "":
+		payload = qart.Query(*url, *query)
+	default:
+		payload = qart.Fragment(*url)
+	}
+
+	lev := strings.Index("LMQH", strings.ToUpper(*level))
+	if len(*level) != 1 || lev < 0 {
+		log.Fatalf("invalid level %q", *level)
+	}
+
+	m := &qart.Image{
+		Payload:      payload,
+		Version:      *version,
+		Level:        qr.Level(lev),
+		Mask:         *mask,
+		Rotation:     *rotate & 3,
+		Dx:           *dx,
+		Dy:           *dy,
+		Size:         *size,
+		Scale:        *scale,
+		Dither:       *dither,
+		Rand:         *random,
+		OnlyDataBits: *data,
+		SaveControl:  *control != "",
+		SaveHalftone: *htFile != "",
+		SaveColor:    *clrFile != "",
+		SaveReport:   *report != "",
+		ColorOptions: &qart.ColorOptions{Threshold: *thresh, Margin: *margin},
+		Seed:         *seed,
+	}
+	if *margin == 0 {
+		m.ColorOptions.Margin = -1 // no margin, not the default
+	}
+	if *alpha < 0 || *alpha > 255 {
+		log.Fatalf("invalid alpha %d", *alpha)
+	}
+	m.AlphaThreshold = uint8(*alpha)
+	if *banner != "" {
+		// The banner is drawn to fit the code exactly,
+		// so center it unless asked otherwise.
+		if !isSet("dx") {
+			m.Dx = m.Size / 2
+		}
+		if !isSet("dy") {
+			m.Dy = m.Size / 2
+		}
+		if err := m.SetText(*banner, nil); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		src, err := os.ReadFile(*imgFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := m.SetFile(src); err != nil {
+			log.Fatalf("%s: %v", *imgFile, err)
+		}
+	}
+	if *weight != "" {
+		data, err := os.ReadFile(*weight)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			log.Fatalf("%s: %v", *weight, err)
+		}
+		m.SetWeight(w)
+	}
+
+	if *search {
+		m = best(m)
+	} else if _, err := m.Encode(); err != nil {
+		log.Fatal(err)
+	}
+
+	if *banner != "" {
+		if miss := m.GlyphMisses(); len(miss) > 0 {
+			fmt.Fprintf(os.Stderr, "could not draw %d text modules\n", len(miss))
+		}
+	}
+
+	write(*out, m.Code.PNG())
+	if *control != "" {
+		write(*control, m.Control)
+	}
+	if *svgFile != "" {
+		write(*svgFile, m.Code.SVG())
+	}
+	if *htFile != "" {
+		write(*htFile, m.Halftone)
+	}
+	if *clrFile != "" {
+		write(*clrFile, m.Color)
+	}
+	if *report != "" {
+		var b bytes.Buffer
+		if err := png.Encode(&b, m.Report.HeatMap(m.Code.Scale)); err != nil {
+			log.Fatal(err)
+		}
+		write(*report, b.Bytes())
+		fmt.Fprint(os.Stderr, m.Report)
+	}
+}
+
+// best searches for the placement of m that best
+// resembles the image and returns it, encoded.
+func best(m *qart.Image) *qart.Image {
+	cands, err := m.Search(&qart.SearchOptions{Offset: *srange, Size: *srange, Top: 5})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(cands) == 0 {
+		log.Fatal("no placement found")
+	}
+	for _, c := range cands {
+		fmt.Fprintf(os.Stderr, "%.4f: -dx %d -dy %d -size %d -mask %d -rotate %d -seed %d\n",
+			1-c.Mismatch, c.Dx, c.Dy, c.Size, c.Mask, c.Rotation, c.Image.UsedSeed)
+	}
+	return cands[0].Image
+}
+
+// isSet reports whether the named flag was set on the command line.
+func isSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func write(file string, data []byte) {
+	if err := os.WriteFile(file, data, 0666); err != nil {
+		log.Fatal(err)
+	}
+}
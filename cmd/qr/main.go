@@ -0,0 +1,268 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Qr encodes text as a QR code.
+//
+// Usage:
+//
+//	qr [options] [text...]
+//	qr [options] -batch file -o dir|file.zip
+//
+// Qr encodes the text given by its arguments, joined by spaces,
+// or else the text read from standard input, minus any final newline.
+//
+// The options are:
+//
+//	-o file
+//		write the code to file, in a format chosen by the file's
+//		extension: .png, .svg, or .txt; with no -o or -o -,
+//		qr draws the code on the terminal
+//	-l level
+//		use error correction level L, M, Q, or H (default L)
+//	-scale n
+//		draw each QR module as an n×n square in PNG and SVG (default 8)
+//	-border n
+//		surround the code with a quiet zone of n modules (default 4)
+//	-minversion n, -maxversion n
+//		use a QR version between n and m (default 1 and 40)
+//	-mask n
+//		use QR mask n, from 0 to 7 (default: pick the best)
+//	-info
+//		print the chosen version, level, mask, data mode,
+//		and how much of the code's capacity the text uses
+//
+//	-batch file
+//		encode the records in file instead (see below)
+//	-format list
+//		with -batch, write images in the comma-separated formats
+//		in list: png, svg, or png,svg (default png)
+//	-j n
+//		with -batch, encode using n goroutines (default: one per CPU)
+//
+// For example:
+//
+//	qr -l H -scale 8 -o out.png "hello, world"
+//
+// The -batch flag encodes many codes at once. The file holds
+// CSV records, with a header line naming the columns id, payload,
+// and optionally level and version, or else, if its name ends in
+// .jsonl, JSON objects with those fields, one per line. Qr writes
+// each code to a file named for its id, such as id.png, and writes
+// a manifest.json describing every code, all into the directory
+// or zip archive named by -o. The -l flag gives the level, and the
+// -minversion and -maxversion flags bound the version, for records
+// that do not give one; -scale, -border, and -mask apply to every
+// record. Qr reports records that cannot be encoded on standard
+// error and exits with status 1, but it still writes the other records.
+//
+//	qr -batch shipment.csv -format png,svg -o shipment.zip
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"rsc.io/qr"
+	"rsc.io/qr/batch"
+)
+
+var (
+	out        = flag.String("o", "", "write code to `file` (.png, .svg, or .txt)")
+	level      = flag.String("l", "L", "error correction `level` (L, M, Q, H)")
+	scale      = flag.Int("scale", 8, "image pixels per module")
+	border     = flag.Int("border", 4, "quiet zone `width` in modules")
+	minVersion = flag.Int("minversion", 1, "smallest QR `version`")
+	maxVersion = flag.Int("maxversion", 40, "largest QR `version`")
+	mask       = flag.Int("mask", qr.AutoMask, "QR `mask` (-1 for best)")
+	info       = flag.Bool("info", false, "print encoding information")
+	batchFile  = flag.String("batch", "", "encode the records in `file` (CSV or .jsonl)")
+	format     = flag.String("format", "png", "with -batch, image formats (png, svg, or png,svg)")
+	workers    = flag.Int("j", 0, "with -batch, number of goroutines encoding")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: qr [options] [text...]\n")
+	fmt.Fprintf(os.Stderr, "       qr [options] -batch file -o dir|file.zip\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("qr: ")
+	flag.Usage = usage
+	flag.Parse()
+
+	lev := strings.Index("LMQH", strings.ToUpper(*level))
+	if len(*level) != 1 || lev < 0 {
+		log.Fatalf("invalid level %q", *level)
+	}
+	if *scale < 1 || *border < 0 {
+		log.Fatalf("invalid -scale or -border")
+	}
+
+	if *batchFile != "" {
+		if flag.NArg() > 0 || *out == "" || *out == "-" {
+			usage()
+		}
+		os.Exit(runBatch(qr.Level(lev)))
+	}
+
+	var text string
+	if flag.NArg() > 0 {
+		text = strings.Join(flag.Args(), " ")
+	} else {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatal(err)
+		}
+		text = strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+	}
+
+	c, inf, err := qr.EncodeOptions(text, qr.Level(lev), &qr.Options{
+		MinVersion: *minVersion,
+		MaxVersion: *maxVersion,
+		Mask:       *mask,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	c.Scale = *scale
+
+	if *info {
+		fmt.Fprintf(os.Stderr, "version %d (%d×%d), level %c, mask %d, %s mode, %d of %d data bits (%.0f%%)\n",
+			inf.Version, c.Size, c.Size, "LMQH"[inf.Level], inf.Mask, inf.Mode,
+			inf.Bits, inf.Capacity, 100*float64(inf.Bits)/float64(inf.Capacity))
+	}
+
+	if *out == "" || *out == "-" {
+		os.Stdout.Write(terminal(c, *border))
+		return
+	}
+	var data []byte
+	switch ext := strings.ToLower(filepath.Ext(*out)); ext {
+	case ".png":
+		data = c.PNGBorder(*border)
+	case ".svg":
+		data = c.SVGBorder(*border)
+	case ".txt":
+		data = txt(c, *border)
+	default:
+		log.Fatalf("unknown output format %q", ext)
+	}
+	if err := os.WriteFile(*out, data, 0666); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runBatch encodes the records in *batchFile,
+// returning the exit status.
+func runBatch(lev qr.Level) int {
+	f, err := os.Open(*batchFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var recs []batch.Record
+	if strings.HasSuffix(*batchFile, ".jsonl") {
+		recs, err = batch.ReadJSONL(f)
+	} else {
+		recs, err = batch.ReadCSV(f)
+	}
+	f.Close()
+	if err != nil {
+		log.Fatalf("%s: %v", *batchFile, err)
+	}
+
+	opt := &batch.Options{
+		Level:      lev,
+		Scale:      *scale,
+		Border:     *border,
+		Formats:    strings.Split(*format, ","),
+		Workers:    *workers,
+		MinVersion: *minVersion,
+		MaxVersion: *maxVersion,
+		Mask:       *mask,
+	}
+	if *border == 0 {
+		opt.Border = -1 // no border, not the default
+	}
+	var results []batch.Result
+	if strings.HasSuffix(*out, ".zip") {
+		zf, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		z := batch.Zip(zf)
+		results, err = batch.Run(recs, z, opt)
+		if err == nil {
+			err = z.Close()
+		}
+		if err1 := zf.Close(); err == nil {
+			err = err1
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		results, err = batch.Run(recs, batch.Dir(*out), opt)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	status := 0
+	for _, r := range results {
+		if r.Err != nil {
+			log.Printf("%s:%d: %v", *batchFile, r.Line, r.Err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// txt returns a text drawing of c with a border-module quiet zone,
+// using two characters per module: "##" for black and "  " for white.
+func txt(c *qr.Code, border int) []byte {
+	var b bytes.Buffer
+	for y := -border; y < c.Size+border; y++ {
+		for x := -border; x < c.Size+border; x++ {
+			if c.Black(x, y) {
+				b.WriteString("##")
+			} else {
+				b.WriteString("  ")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
+
+// terminal returns a drawing of c with a border-module quiet zone
+// for display on a terminal. Each character cell shows two modules,
+// one above the other, as an upper half block drawn with explicit
+// foreground and background colors, so that the code is black on
+// white even on terminals with dark backgrounds.
+func terminal(c *qr.Code, border int) []byte {
+	var b bytes.Buffer
+	for y := -border; y < c.Size+border; y += 2 {
+		for x := -border; x < c.Size+border; x++ {
+			fg, bg := 97, 107 // bright white
+			if c.Black(x, y) {
+				fg = 30
+			}
+			if y+1 < c.Size+border && c.Black(x, y+1) {
+				bg = 40
+			}
+			fmt.Fprintf(&b, "\x1b[%d;%dm▀", fg, bg)
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,130 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Qrdecode decodes QR codes in image files.
+//
+// Usage:
+//
+//	qrdecode [-json] file...
+//
+// Qrdecode reads each file, which may be a PNG, JPEG, or GIF image,
+// and prints the text of the QR code it contains. When decoding more
+// than one file, it prefixes each text with the file name and a colon.
+// It reports files that it cannot read or decode on standard error.
+//
+// The -json flag prints one JSON object per file instead, on a line
+// of its own, giving the file name, the text, the QR version, level,
+// and mask, the outer corners of the code in image coordinates
+// (top left, top right, bottom right, bottom left), and the number of
+// bytes corrected by error correction, in total and per block.
+// For a file that cannot be decoded, the object gives the file name
+// and the error.
+//
+// Qrdecode exits with status 0 if it decoded every file,
+// 1 if any file failed, and 2 for a usage error.
+//
+// For example, to check generated label artwork:
+//
+//	qrdecode -json labels/*.png
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"log"
+	"os"
+
+	"rsc.io/qr/decode"
+)
+
+var jsonFlag = flag.Bool("json", false, "print results as JSON")
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: qrdecode [-json] file...\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+// A result is the JSON form of a decoded file.
+type result struct {
+	File        string   `json:"file"`
+	Text        string   `json:"text"`
+	Version     int      `json:"version"`
+	Level       string   `json:"level"`
+	Mask        int      `json:"mask"`
+	Corners     [][2]int `json:"corners"`
+	Errors      int      `json:"errors"`
+	BlockErrors []int    `json:"blockErrors"`
+}
+
+// A failure is the JSON form of a file that could not be decoded.
+type failure struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("qrdecode: ")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	status := 0
+	for _, file := range flag.Args() {
+		r, err := decodeFile(file)
+		if err != nil {
+			status = 1
+			if *jsonFlag {
+				enc.Encode(&failure{File: file, Error: err.Error()})
+			} else {
+				log.Printf("%s: %v", file, err)
+			}
+			continue
+		}
+		if *jsonFlag {
+			j := &result{
+				File:        file,
+				Text:        r.Text,
+				Version:     r.Version,
+				Level:       "LMQH"[r.Level : r.Level+1],
+				Mask:        r.Mask,
+				Errors:      r.Errors,
+				BlockErrors: r.BlockErrors,
+			}
+			for _, p := range r.Corners {
+				j.Corners = append(j.Corners, [2]int{p.X, p.Y})
+			}
+			enc.Encode(j)
+			continue
+		}
+		if flag.NArg() > 1 {
+			fmt.Printf("%s: ", file)
+		}
+		fmt.Printf("%s\n", r.Text)
+	}
+	os.Exit(status)
+}
+
+func decodeFile(file string) (*decode.Result, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	return decode.Decode(m)
+}
@@ -282,7 +282,7 @@ var roles = []string{
 }
 
 func (r PixelRole) String() string {
-	if Position <= r && r <= Check {
+	if Position <= r && r <= Extra {
 		return roles[r]
 	}
 	return strconv.Itoa(int(r))
@@ -0,0 +1,475 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package decode reads QR codes from images.
+//
+// Decode finds the three position squares of a code in an image,
+// uses them and the bottom right alignment square to map the code's
+// modules onto the image, and then reads the modules, correcting
+// errors using the code's Reed-Solomon check bytes.
+// It handles codes that are scaled, rotated, mildly skewed, noisy,
+// or mirrored, but it reads only one code per image.
+//
+// The decoder supports the numeric, alphanumeric, and byte
+// encodings. It skips ECI, FNC1, and structured append headers
+// and does not support the kanji encoding.
+package decode // import "rsc.io/qr/decode"
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math/bits"
+
+	"rsc.io/qr"
+	"rsc.io/qr/coding"
+	"rsc.io/qr/gf256"
+)
+
+// A Result describes a decoded QR code.
+type Result struct {
+	Text    string   // decoded text
+	Version int      // QR version, from 1 to 40
+	Level   qr.Level // error correction level
+	Mask    int      // QR mask, from 0 to 7
+
+	// Corners gives the outer corners of the code in the image,
+	// not counting the quiet zone: top left, top right,
+	// bottom right, and bottom left, as seen in the code's
+	// own orientation.
+	Corners [4]image.Point
+
+	// Errors is the number of bytes corrected by error correction.
+	// BlockErrors breaks that count down by Reed-Solomon block.
+	Errors      int
+	BlockErrors []int
+}
+
+// ErrNotFound is returned by Decode when it cannot
+// find the position squares of a QR code in the image.
+var ErrNotFound = errors.New("qr: no code found")
+
+// Decode decodes the QR code in m.
+func Decode(m image.Image) (*Result, error) {
+	w, h, pix := luma(m)
+	var err error
+	for try := 0; try < 3; try++ {
+		var b *bitmap
+		switch try {
+		case 0:
+			b = otsu(w, h, pix)
+		case 1:
+			b = adaptive(w, h, pix)
+		case 2:
+			// Smooth away speckle noise.
+			b = otsu(w, h, blur(w, h, pix))
+		}
+		var r *Result
+		r, err = decodeBitmap(b)
+		if err == nil {
+			min := m.Bounds().Min
+			for i := range r.Corners {
+				r.Corners[i] = r.Corners[i].Add(min)
+			}
+			return r, nil
+		}
+	}
+	return nil, err
+}
+
+func decodeBitmap(b *bitmap) (*Result, error) {
+	tl, tr, bl, ok := pickFinders(b.findFinders())
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	// The version estimate from the position squares can be off,
+	// so try the neighboring versions too. Versions 7 and up
+	// record the version in the code, so trust that instead
+	// when it is readable.
+	est := estimateVersion(tl, tr, bl)
+	var firstErr error
+	tried := make(map[int]bool)
+	try := []int{est, est - 1, est + 1, est - 2, est + 2}
+	for len(try) > 0 {
+		v := try[0]
+		try = try[1:]
+		if v < 1 || v > 40 || tried[v] {
+			continue
+		}
+		tried[v] = true
+		g, h, ok := b.sample(tl, tr, bl, 17+4*v)
+		if !ok {
+			continue
+		}
+		r, err := decodeGrid(g)
+		if err != nil {
+			// A mirrored code samples as the transpose of the code.
+			var err1 error
+			r, err1 = decodeGrid(transpose(g))
+			if err1 != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				var ve *versionError
+				if errors.As(err, &ve) {
+					try = append([]int{ve.version}, try...)
+				}
+				continue
+			}
+			h = h.transpose()
+		}
+		d := float64(len(g))
+		r.Corners = [4]image.Point{
+			h.apply(0, 0).image(),
+			h.apply(d, 0).image(),
+			h.apply(d, d).image(),
+			h.apply(0, d).image(),
+		}
+		return r, nil
+	}
+	if firstErr == nil {
+		firstErr = ErrNotFound
+	}
+	return nil, firstErr
+}
+
+// transpose returns the transpose of the square grid g.
+func transpose(g [][]bool) [][]bool {
+	t := make([][]bool, len(g))
+	for y := range t {
+		t[y] = make([]bool, len(g))
+		for x := range t[y] {
+			t[y][x] = g[x][y]
+		}
+	}
+	return t
+}
+
+// transpose returns the transform that applies h
+// to transposed module coordinates.
+func (h *transform) transpose() *transform {
+	return &transform{h[1], h[0], h[2], h[4], h[3], h[5], h[7], h[6], h[8]}
+}
+
+// A versionError reports that a code's version pattern
+// records a version other than the one implied by its size.
+type versionError struct {
+	version int
+}
+
+func (e *versionError) Error() string {
+	return fmt.Sprintf("qr: version pattern says version %d", e.version)
+}
+
+// decodeGrid decodes the code with modules g, where g[y][x]
+// reports whether the module at (x, y) is black.
+func decodeGrid(g [][]bool) (*Result, error) {
+	n := len(g)
+	if n < 21 || n > 177 || n%4 != 1 {
+		return nil, fmt.Errorf("qr: invalid code size %d", n)
+	}
+	v := (n - 17) / 4
+	if v >= 7 {
+		if pv := readVersion(g); pv == 0 {
+			return nil, errors.New("qr: unreadable version pattern")
+		} else if pv != v {
+			return nil, &versionError{pv}
+		}
+	}
+	l, mask, err := readFormat(g, coding.Version(v))
+	if err != nil {
+		return nil, err
+	}
+	p, err := coding.NewPlan(coding.Version(v), l, mask)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the data and check bytes, undoing the mask.
+	raw := make([]byte, p.DataBytes+p.CheckBytes)
+	for y, row := range p.Pixel {
+		for x, pix := range row {
+			if r := pix.Role(); r != coding.Data && r != coding.Check {
+				continue
+			}
+			if g[y][x] != (pix&coding.Black != 0) {
+				o := pix.Offset()
+				raw[o/8] |= 1 << uint(7-o&7)
+			}
+		}
+	}
+
+	// Correct each block. The plan lays out the data bytes of
+	// each block in turn, shortest blocks first, followed by
+	// the check bytes of each block.
+	nc := p.CheckBytes / p.Blocks
+	nd := p.DataBytes / p.Blocks
+	extra := p.DataBytes % p.Blocks
+	rs := gf256.NewRSDecoder(coding.Field, nc)
+	res := &Result{Version: v, Level: qr.Level(l), Mask: int(mask)}
+	data := make([]byte, 0, p.DataBytes)
+	off := 0
+	for i := 0; i < p.Blocks; i++ {
+		size := nd
+		if i >= p.Blocks-extra {
+			size++
+		}
+		block := make([]byte, 0, size+nc)
+		block = append(block, raw[off:off+size]...)
+		block = append(block, raw[p.DataBytes+i*nc:p.DataBytes+(i+1)*nc]...)
+		off += size
+		ne, err := rs.Correct(block)
+		if err != nil {
+			return nil, fmt.Errorf("qr: block %d: %v", i, err)
+		}
+		res.Errors += ne
+		res.BlockErrors = append(res.BlockErrors, ne)
+		data = append(data, block[:size]...)
+	}
+
+	res.Text, err = parse(data, v)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// readVersion returns the version recorded in either
+// of g's version patterns, or 0 if neither is readable.
+func readVersion(g [][]bool) int {
+	n := len(g)
+	var a, b uint32
+	for x := 0; x < 6; x++ {
+		for y := 0; y < 3; y++ {
+			i := uint(x*3 + y)
+			if g[n-11+y][x] {
+				a |= 1 << i
+			}
+			if g[x][n-11+y] {
+				b |= 1 << i
+			}
+		}
+	}
+	best, bestDist := 0, 4
+	for v := 7; v <= 40; v++ {
+		p := versionPattern(v)
+		for _, w := range []uint32{a, b} {
+			if d := bits.OnesCount32(w ^ p); d < bestDist {
+				best, bestDist = v, d
+			}
+		}
+	}
+	return best
+}
+
+// versionPattern returns the 18-bit version pattern for version v:
+// v followed by a BCH(18,6) check.
+func versionPattern(v int) uint32 {
+	const poly = 0x1f25
+	p := uint32(v) << 12
+	rem := p
+	for i := 17; i >= 12; i-- {
+		if rem&(1<<uint(i)) != 0 {
+			rem ^= poly << uint(i-12)
+		}
+	}
+	return p | rem
+}
+
+// formatPattern returns the 15 format bits for level l
+// and mask m, as drawn (black is 1).
+func formatPattern(l coding.Level, m coding.Mask) uint32 {
+	const poly = 0x537
+	fb := uint32(l^1)<<13 | uint32(m)<<10
+	rem := fb
+	for i := 14; i >= 10; i-- {
+		if rem&(1<<uint(i)) != 0 {
+			rem ^= poly << uint(i-10)
+		}
+	}
+	return (fb | rem) ^ 0x5412
+}
+
+// readFormat returns the level and mask recorded
+// in either copy of g's format bits.
+func readFormat(g [][]bool, v coding.Version) (coding.Level, coding.Mask, error) {
+	// The plan for any level and mask gives the format bit positions.
+	p, err := coding.NewPlan(v, coding.L, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	var copies [2]uint32
+	var seen [15]int
+	for y, row := range p.Pixel {
+		for x, pix := range row {
+			if pix.Role() != coding.Format {
+				continue
+			}
+			i := pix.Offset()
+			if g[y][x] {
+				copies[seen[i]] |= 1 << i
+			}
+			seen[i]++
+		}
+	}
+	bestDist := 4
+	var bestL coding.Level
+	var bestM coding.Mask
+	for l := coding.L; l <= coding.H; l++ {
+		for m := coding.Mask(0); m < 8; m++ {
+			f := formatPattern(l, m)
+			for _, c := range copies {
+				if d := bits.OnesCount32(c ^ f); d < bestDist {
+					bestDist, bestL, bestM = d, l, m
+				}
+			}
+		}
+	}
+	if bestDist > 3 {
+		return 0, 0, errors.New("qr: unreadable format bits")
+	}
+	return bestL, bestM, nil
+}
+
+// A bitReader reads big-endian bit fields from a byte slice.
+type bitReader struct {
+	p   []byte
+	off int // bit offset
+}
+
+func (r *bitReader) left() int {
+	return 8*len(r.p) - r.off
+}
+
+// read reads an n-bit field, returning ok=false at the end of the data.
+func (r *bitReader) read(n int) (v int, ok bool) {
+	if n > r.left() {
+		return 0, false
+	}
+	for i := 0; i < n; i++ {
+		v = v<<1 | int(r.p[r.off/8]>>uint(7-r.off&7)&1)
+		r.off++
+	}
+	return v, true
+}
+
+const alnum = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// parse decodes the segments in the data bytes of a version v code.
+func parse(data []byte, v int) (string, error) {
+	class := 0
+	switch {
+	case v >= 27:
+		class = 2
+	case v >= 10:
+		class = 1
+	}
+	r := &bitReader{p: data}
+	var text []byte
+	short := errors.New("qr: truncated data")
+	for r.left() >= 4 {
+		mode, _ := r.read(4)
+		switch mode {
+		case 0: // terminator
+			return string(text), nil
+
+		case 1: // numeric
+			n, ok := r.read([]int{10, 12, 14}[class])
+			if !ok {
+				return "", short
+			}
+			for ; n > 0; n -= 3 {
+				digits, nbit := 3, 10
+				switch n {
+				case 1:
+					digits, nbit = 1, 4
+				case 2:
+					digits, nbit = 2, 7
+				}
+				x, ok := r.read(nbit)
+				if !ok {
+					return "", short
+				}
+				s := fmt.Sprintf("%0*d", digits, x)
+				if len(s) != digits {
+					return "", errors.New("qr: invalid numeric data")
+				}
+				text = append(text, s...)
+			}
+
+		case 2: // alphanumeric
+			n, ok := r.read([]int{9, 11, 13}[class])
+			if !ok {
+				return "", short
+			}
+			for ; n > 1; n -= 2 {
+				x, ok := r.read(11)
+				if !ok {
+					return "", short
+				}
+				if x >= 45*45 {
+					return "", errors.New("qr: invalid alphanumeric data")
+				}
+				text = append(text, alnum[x/45], alnum[x%45])
+			}
+			if n == 1 {
+				x, ok := r.read(6)
+				if !ok {
+					return "", short
+				}
+				if x >= 45 {
+					return "", errors.New("qr: invalid alphanumeric data")
+				}
+				text = append(text, alnum[x])
+			}
+
+		case 4: // byte
+			n, ok := r.read([]int{8, 16, 16}[class])
+			if !ok {
+				return "", short
+			}
+			for ; n > 0; n-- {
+				x, ok := r.read(8)
+				if !ok {
+					return "", short
+				}
+				text = append(text, byte(x))
+			}
+
+		case 7: // ECI: 1, 2, or 3 byte designator
+			x, ok := r.read(8)
+			switch {
+			case x&0x80 == 0:
+			case x&0xc0 == 0x80:
+				_, ok = r.read(8)
+			case x&0xe0 == 0xc0:
+				_, ok = r.read(16)
+			default:
+				ok = false
+			}
+			if !ok {
+				return "", errors.New("qr: invalid ECI designator")
+			}
+
+		case 3: // structured append: position, total, parity
+			if _, ok := r.read(16); !ok {
+				return "", short
+			}
+
+		case 5: // FNC1 in first position
+		case 9: // FNC1 in second position: application indicator
+			if _, ok := r.read(8); !ok {
+				return "", short
+			}
+
+		case 8:
+			return "", errors.New("qr: kanji encoding not supported")
+
+		default:
+			return "", fmt.Errorf("qr: invalid mode %d", mode)
+		}
+	}
+	return string(text), nil
+}
@@ -0,0 +1,229 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decode
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"rsc.io/qr"
+)
+
+// encode returns the PNG image of text encoded at level l with mask.
+func encode(t *testing.T, text string, l qr.Level, mask int) (image.Image, *qr.Info) {
+	t.Helper()
+	c, info, err := qr.EncodeOptions(text, l, &qr.Options{Mask: mask})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := png.Decode(bytes.NewReader(c.PNG()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, info
+}
+
+func check(t *testing.T, name string, m image.Image, text string, info *qr.Info) *Result {
+	t.Helper()
+	r, err := Decode(m)
+	if err != nil {
+		t.Fatalf("%s: Decode: %v", name, err)
+	}
+	if r.Text != text || r.Version != info.Version || r.Level != info.Level || r.Mask != info.Mask {
+		t.Fatalf("%s: Decode = %q v%d %v mask %d, want %q v%d %v mask %d", name,
+			r.Text, r.Version, r.Level, r.Mask, text, info.Version, info.Level, info.Mask)
+	}
+	return r
+}
+
+var texts = []string{
+	"hello, world",
+	"0123456789012345",
+	"HTTPS://EXAMPLE.COM/ABC",
+	"https://example.com/a/b/c?d=e&f=g",
+	strings.Repeat("The quick brown fox jumps over the lazy dog. ", 8),
+	strings.Repeat("31415926535897932384626433832795028841971", 20),
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, text := range texts {
+		for l := qr.L; l <= qr.H; l++ {
+			m, info := encode(t, text, l, qr.AutoMask)
+			r := check(t, "plain", m, text, info)
+			if r.Errors != 0 {
+				t.Errorf("clean image: %d errors", r.Errors)
+			}
+			// The PNG has a 4-module quiet zone
+			// and 8 pixels per module.
+			n := 17 + 4*info.Version
+			want := [4]image.Point{{32, 32}, {32 + 8*n, 32}, {32 + 8*n, 32 + 8*n}, {32, 32 + 8*n}}
+			for i, p := range r.Corners {
+				if d := p.Sub(want[i]); abs(d.X) > 2 || abs(d.Y) > 2 {
+					t.Errorf("v%d: Corners = %v, want %v", info.Version, r.Corners, want)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestMasks(t *testing.T) {
+	for mask := 0; mask < 8; mask++ {
+		m, info := encode(t, "mask test 12345", qr.M, mask)
+		check(t, "mask", m, "mask test 12345", info)
+	}
+}
+
+func TestGrid(t *testing.T) {
+	// Decode every version directly from the modules,
+	// with errors spread across the blocks.
+	rnd := rand.New(rand.NewSource(1))
+	for v := 1; v <= 40; v++ {
+		text := strings.Repeat("x", 7*v)
+		c, info, err := qr.EncodeOptions(text, qr.Q, &qr.Options{MinVersion: v})
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := make([][]bool, c.Size)
+		for y := range g {
+			g[y] = make([]bool, c.Size)
+			for x := range g[y] {
+				g[y][x] = c.Black(x, y)
+			}
+		}
+		// Flip a few data modules far from the function patterns.
+		for i := 0; i < 3; i++ {
+			x, y := 9+rnd.Intn(c.Size-18), 9+rnd.Intn(c.Size-18)
+			if x != 6 && y != 6 {
+				g[y][x] = !g[y][x]
+			}
+		}
+		r, err := decodeGrid(g)
+		if err != nil {
+			t.Fatalf("v%d: %v", v, err)
+		}
+		if r.Text != text || r.Version != info.Version {
+			t.Fatalf("v%d: decodeGrid = %q v%d", v, r.Text, r.Version)
+		}
+		if len(r.BlockErrors) == 0 {
+			t.Fatalf("v%d: no BlockErrors", v)
+		}
+	}
+}
+
+func TestVersionPattern(t *testing.T) {
+	for v, want := range map[int]uint32{7: 0x7c94, 8: 0x85bc, 21: 0x15683, 40: 0x28c69} {
+		if p := versionPattern(v); p != want {
+			t.Errorf("versionPattern(%d) = %#x, want %#x", v, p, want)
+		}
+	}
+}
+
+// rotate returns m rotated by angle radians about its center
+// and scaled by k, on a white background.
+func rotate(m image.Image, angle, k float64) *image.Gray {
+	b := m.Bounds()
+	size := int(float64(b.Dx())*k*1.5) + 1
+	dst := image.NewGray(image.Rect(0, 0, size, size))
+	sin, cos := math.Sincos(angle)
+	c := float64(size) / 2
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := (float64(x)+0.5-c)/k, (float64(y)+0.5-c)/k
+			sx := cos*dx + sin*dy + float64(b.Dx())/2
+			sy := -sin*dx + cos*dy + float64(b.Dy())/2
+			p := image.Pt(b.Min.X+int(math.Floor(sx)), b.Min.Y+int(math.Floor(sy)))
+			v := uint8(255)
+			if p.In(b) {
+				v = color.GrayModel.Convert(m.At(p.X, p.Y)).(color.Gray).Y
+			}
+			dst.Pix[y*dst.Stride+x] = v
+		}
+	}
+	return dst
+}
+
+func TestTransformed(t *testing.T) {
+	text := "https://example.com/transformed?id=12345"
+	for _, l := range []qr.Level{qr.L, qr.H} {
+		m, info := encode(t, text, l, qr.AutoMask)
+		for _, deg := range []float64{10, 45, 90, 135, 180, 217, 270, 333} {
+			for _, k := range []float64{0.5, 1.3} {
+				check(t, "rotate", rotate(m, deg*math.Pi/180, k), text, info)
+			}
+		}
+	}
+}
+
+func TestMirror(t *testing.T) {
+	text := "mirror image"
+	m, info := encode(t, text, qr.M, qr.AutoMask)
+	b := m.Bounds()
+	dst := image.NewGray(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			dst.Set(b.Max.X-1-(x-b.Min.X), y, m.At(x, y))
+		}
+	}
+	check(t, "mirror", dst, text, info)
+}
+
+func TestNoise(t *testing.T) {
+	text := strings.Repeat("noisy ", 20)
+	m, info := encode(t, text, qr.H, qr.AutoMask)
+	b := m.Bounds()
+	dst := image.NewGray(b)
+	draw.Draw(dst, b, m, b.Min, draw.Src)
+
+	// Speckle the image and paint over some whole modules.
+	rnd := rand.New(rand.NewSource(1))
+	for i := range dst.Pix {
+		if rnd.Intn(20) == 0 {
+			dst.Pix[i] ^= 0xff
+		}
+	}
+	n := 17 + 4*info.Version
+	for i := 0; i < 20; i++ {
+		x, y := 9+rnd.Intn(n-18), 9+rnd.Intn(n-18)
+		r := image.Rect(32+8*x, 32+8*y, 40+8*x, 40+8*y)
+		draw.Draw(dst, r, image.NewUniform(color.Gray{uint8(255 * rnd.Intn(2))}), image.Point{}, draw.Src)
+	}
+	r := check(t, "noise", dst, text, info)
+	if r.Errors == 0 {
+		t.Errorf("noise: no errors corrected")
+	}
+}
+
+func TestLowContrast(t *testing.T) {
+	// A gray-on-gray code with a lighting gradient.
+	text := "low contrast"
+	m, info := encode(t, text, qr.M, qr.AutoMask)
+	b := m.Bounds()
+	dst := image.NewGray(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			v := 100 + 60*float64(x-b.Min.X)/float64(b.Dx())
+			if color.GrayModel.Convert(m.At(x, y)).(color.Gray).Y > 128 {
+				v += 50
+			}
+			dst.SetGray(x, y, color.Gray{uint8(v)})
+		}
+	}
+	check(t, "gradient", dst, text, info)
+}
+
+func TestNotFound(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 100, 100))
+	if _, err := Decode(m); err != ErrNotFound {
+		t.Errorf("Decode(blank) = %v, want ErrNotFound", err)
+	}
+}
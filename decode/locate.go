@@ -0,0 +1,523 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package decode
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// A bitmap is a binarized image: pix[y*w+x] reports
+// whether the pixel at (x, y) is dark.
+type bitmap struct {
+	w, h int
+	pix  []bool
+}
+
+func (b *bitmap) in(x, y int) bool {
+	return 0 <= x && x < b.w && 0 <= y && y < b.h
+}
+
+// at reports whether the pixel at (x, y) is dark.
+// Pixels outside the image are light.
+func (b *bitmap) at(x, y int) bool {
+	return b.in(x, y) && b.pix[y*b.w+x]
+}
+
+// atf reports whether the pixel containing the point (x, y) is dark.
+func (b *bitmap) atf(x, y float64) bool {
+	return b.at(int(math.Floor(x)), int(math.Floor(y)))
+}
+
+// luma returns the gray values of m, composited over white.
+func luma(m image.Image) (w, h int, pix []uint8) {
+	r := m.Bounds()
+	w, h = r.Dx(), r.Dy()
+	pix = make([]uint8, w*h)
+	if g, ok := m.(*image.Gray); ok {
+		for y := 0; y < h; y++ {
+			copy(pix[y*w:(y+1)*w], g.Pix[g.PixOffset(r.Min.X, r.Min.Y+y):])
+		}
+		return w, h, pix
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			cr, cg, cb, ca := m.At(r.Min.X+x, r.Min.Y+y).RGBA()
+			white := 0xffff - ca
+			cr, cg, cb = cr+white, cg+white, cb+white
+			// Same coefficients as color.GrayModel.
+			pix[y*w+x] = uint8((19595*cr + 38470*cg + 7471*cb + 1<<15) >> 24)
+		}
+	}
+	return w, h, pix
+}
+
+// otsu binarizes pix using a single threshold
+// chosen by Otsu's method.
+func otsu(w, h int, pix []uint8) *bitmap {
+	var hist [256]int
+	for _, v := range pix {
+		hist[v]++
+	}
+	total := float64(len(pix))
+	sum := 0.0
+	for i, n := range hist {
+		sum += float64(i * n)
+	}
+	var sumB, wB float64
+	best, t := -1.0, 0
+	for i, n := range hist {
+		wB += float64(n)
+		if wB == 0 {
+			continue
+		}
+		wF := total - wB
+		if wF == 0 {
+			break
+		}
+		sumB += float64(i * n)
+		mB, mF := sumB/wB, (sum-sumB)/wF
+		if v := wB * wF * (mB - mF) * (mB - mF); v > best {
+			best, t = v, i
+		}
+	}
+	b := &bitmap{w: w, h: h, pix: make([]bool, len(pix))}
+	for i, v := range pix {
+		b.pix[i] = int(v) <= t
+	}
+	return b
+}
+
+// adaptive binarizes pix by comparing each pixel
+// against the mean of the pixels around it,
+// which copes with uneven lighting.
+func adaptive(w, h int, pix []uint8) *bitmap {
+	// Summed-area table with a zero row and column.
+	sum := make([]int64, (w+1)*(h+1))
+	for y := 0; y < h; y++ {
+		row := int64(0)
+		for x := 0; x < w; x++ {
+			row += int64(pix[y*w+x])
+			sum[(y+1)*(w+1)+x+1] = sum[y*(w+1)+x+1] + row
+		}
+	}
+	r := w
+	if h > r {
+		r = h
+	}
+	r /= 16
+	if r < 4 {
+		r = 4
+	}
+	b := &bitmap{w: w, h: h, pix: make([]bool, len(pix))}
+	for y := 0; y < h; y++ {
+		y0, y1 := clamp(y-r, 0, h), clamp(y+r+1, 0, h)
+		for x := 0; x < w; x++ {
+			x0, x1 := clamp(x-r, 0, w), clamp(x+r+1, 0, w)
+			s := sum[y1*(w+1)+x1] - sum[y0*(w+1)+x1] - sum[y1*(w+1)+x0] + sum[y0*(w+1)+x0]
+			n := int64((y1 - y0) * (x1 - x0))
+			b.pix[y*w+x] = (int64(pix[y*w+x])+4)*n < s
+		}
+	}
+	return b
+}
+
+// blur returns pix blurred by a 3×3 box filter.
+func blur(w, h int, pix []uint8) []uint8 {
+	out := make([]uint8, len(pix))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sum, n := 0, 0
+			for yy := clamp(y-1, 0, h-1); yy <= clamp(y+1, 0, h-1); yy++ {
+				for xx := clamp(x-1, 0, w-1); xx <= clamp(x+1, 0, w-1); xx++ {
+					sum += int(pix[yy*w+xx])
+					n++
+				}
+			}
+			out[y*w+x] = uint8(sum / n)
+		}
+	}
+	return out
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// A point is a location in image coordinates,
+// in which the pixel (x, y) covers [x, x+1)×[y, y+1).
+type point struct {
+	x, y float64
+}
+
+func (p point) add(q point) point            { return point{p.x + q.x, p.y + q.y} }
+func (p point) sub(q point) point            { return point{p.x - q.x, p.y - q.y} }
+func (p point) mul(k float64) point          { return point{p.x * k, p.y * k} }
+func (p point) cross(q point) float64        { return p.x*q.y - p.y*q.x }
+func (p point) dist(q point) float64         { return math.Hypot(p.x-q.x, p.y-q.y) }
+func (p point) dist2(q point) float64        { d := p.sub(q); return d.x*d.x + d.y*d.y }
+func (p point) image() image.Point           { return image.Pt(int(math.Round(p.x)), int(math.Round(p.y))) }
+func (p point) near(q point, d float64) bool { return math.Abs(p.x-q.x) <= d && math.Abs(p.y-q.y) <= d }
+
+// A finder is a candidate position square (finder pattern).
+type finder struct {
+	point
+	module float64 // estimated module size in pixels
+	count  int     // number of scan lines that found it
+}
+
+// finderRatio reports whether the runs c look like
+// a cross-section of a position square: 1:1:3:1:1.
+func finderRatio(c *[5]int) bool {
+	total := 0
+	for _, n := range c {
+		if n == 0 {
+			return false
+		}
+		total += n
+	}
+	if total < 7 {
+		return false
+	}
+	m := float64(total) / 7
+	tol := m / 2
+	return math.Abs(float64(c[0])-m) < tol &&
+		math.Abs(float64(c[1])-m) < tol &&
+		math.Abs(float64(c[2])-3*m) < 3*tol &&
+		math.Abs(float64(c[3])-m) < tol &&
+		math.Abs(float64(c[4])-m) < tol
+}
+
+// crossCheck measures the position square runs through the dark pixel
+// (x, y) along the direction (dx, dy), giving up on runs longer than max.
+// It returns the offset of the square's center from (x, y) along the
+// direction and the total width of the runs.
+func (b *bitmap) crossCheck(x, y, dx, dy, max int) (center float64, total int, ok bool) {
+	var c [5]int
+	run := func(i, step int, black bool, n *int) int {
+		for b.in(x+i*dx, y+i*dy) && b.at(x+i*dx, y+i*dy) == black && *n <= max {
+			*n++
+			i += step
+		}
+		return i
+	}
+	if !b.at(x, y) {
+		return 0, 0, false
+	}
+	i := run(0, -1, true, &c[2])
+	i = run(i, -1, false, &c[1])
+	i = run(i, -1, true, &c[0])
+	j := run(1, +1, true, &c[2])
+	j = run(j, +1, false, &c[3])
+	run(j, +1, true, &c[4])
+	if !finderRatio(&c) {
+		return 0, 0, false
+	}
+	for _, n := range c {
+		total += n
+	}
+	return float64(i+1+c[0]+c[1]) + float64(c[2])/2, total, true
+}
+
+// findFinders returns the candidate position squares in b,
+// most often seen first.
+func (b *bitmap) findFinders() []*finder {
+	var fs []*finder
+	add := func(p point, module float64) {
+		for _, f := range fs {
+			if f.near(p, f.module) && math.Abs(module-f.module) <= math.Max(1, f.module/2) {
+				n := float64(f.count)
+				f.point = f.mul(n).add(p).mul(1 / (n + 1))
+				f.module = (f.module*n + module) / (n + 1)
+				f.count++
+				return
+			}
+		}
+		fs = append(fs, &finder{p, module, 1})
+	}
+	check := func(c *[5]int, end, y int) {
+		if !finderRatio(c) {
+			return
+		}
+		total := c[0] + c[1] + c[2] + c[3] + c[4]
+		cx := float64(end-c[4]-c[3]) - float64(c[2])/2
+		x := int(cx)
+		dy, vtotal, ok := b.crossCheck(x, y, 0, 1, total)
+		if !ok || 5*abs(vtotal-total) >= 2*total {
+			return
+		}
+		cy := float64(y) + dy
+		dx, htotal, ok := b.crossCheck(x, int(cy), 1, 0, total)
+		if !ok || 5*abs(htotal-total) >= 2*total {
+			return
+		}
+		add(point{float64(x) + dx, cy}, float64(htotal+vtotal)/14)
+	}
+	for y := 0; y < b.h; y++ {
+		var c [5]int
+		state := 0
+		for x := 0; x < b.w; x++ {
+			if b.pix[y*b.w+x] {
+				if state&1 == 1 {
+					state++
+				}
+				c[state]++
+				continue
+			}
+			if state&1 == 1 {
+				c[state]++
+				continue
+			}
+			if state == 0 && c[0] == 0 {
+				continue
+			}
+			if state < 4 {
+				state++
+				c[state]++
+				continue
+			}
+			check(&c, x, y)
+			c = [5]int{c[2], c[3], c[4], 1, 0}
+			state = 3
+		}
+		if state == 4 {
+			check(&c, b.w, y)
+		}
+	}
+	sort.SliceStable(fs, func(i, j int) bool { return fs[i].count > fs[j].count })
+	return fs
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// pickFinders chooses the three candidates most likely to be
+// the position squares of a single code, returning them as the
+// top left, top right, and bottom left squares.
+func pickFinders(fs []*finder) (tl, tr, bl *finder, ok bool) {
+	// Prefer candidates seen on more than one scan line.
+	n := 0
+	for n < len(fs) && fs[n].count >= 2 {
+		n++
+	}
+	if n < 3 {
+		n = len(fs)
+	}
+	if n > 12 {
+		n = 12
+	}
+	best := math.Inf(1)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			for k := j + 1; k < n; k++ {
+				a, b, c := fs[i], fs[j], fs[k]
+				lo := math.Min(a.module, math.Min(b.module, c.module))
+				hi := math.Max(a.module, math.Max(b.module, c.module))
+				if hi > 1.5*lo {
+					continue
+				}
+				// Make a the corner opposite the longest side.
+				ab, bc, ca := a.dist2(b.point), b.dist2(c.point), c.dist2(a.point)
+				switch {
+				case ab >= bc && ab >= ca:
+					a, b, c = c, a, b
+					ab, bc, ca = ca, ab, bc
+				case ca >= bc && ca >= ab:
+					a, b, c = b, c, a
+					ab, bc, ca = bc, ca, ab
+				}
+				// The legs must be similar and long enough
+				// for a version 1 code, and the triangle
+				// must be nearly right-angled.
+				if math.Min(ab, ca) < 14*14*lo*lo {
+					continue
+				}
+				legs := ab / ca
+				if legs < 0.5 || legs > 2 {
+					continue
+				}
+				right := bc / (ab + ca)
+				if right < 0.75 || right > 1.33 {
+					continue
+				}
+				score := math.Abs(math.Log(legs)) + math.Abs(math.Log(right))
+				if score < best {
+					best = score
+					tl, tr, bl = a, b, c
+				}
+			}
+		}
+	}
+	if tl == nil {
+		return nil, nil, nil, false
+	}
+	if tr.sub(tl.point).cross(bl.sub(tl.point)) < 0 {
+		tr, bl = bl, tr
+	}
+	return tl, tr, bl, true
+}
+
+// estimateVersion estimates the version of the code
+// with the given position squares.
+func estimateVersion(tl, tr, bl *finder) int {
+	m := (tl.module + tr.module + bl.module) / 3
+	d := (tl.dist(tr.point) + tl.dist(bl.point)) / (2 * m)
+	return clamp(int(math.Round((d+7-17)/4)), 1, 40)
+}
+
+// A transform maps module coordinates to image coordinates.
+// It is a projective transform h, with h[8] = 1.
+type transform [9]float64
+
+func (h *transform) apply(u, v float64) point {
+	d := h[6]*u + h[7]*v + 1
+	return point{(h[0]*u + h[1]*v + h[2]) / d, (h[3]*u + h[4]*v + h[5]) / d}
+}
+
+// newTransform returns the transform mapping each
+// point src[i] to dst[i], or ok=false if there is none.
+func newTransform(src, dst [4]point) (h *transform, ok bool) {
+	// Solve the 8×8 linear system
+	//	h0 u + h1 v + h2 - h6 u x - h7 v x = x
+	//	h3 u + h4 v + h5 - h6 u y - h7 v y = y
+	// by Gaussian elimination with partial pivoting.
+	var a [8][9]float64
+	for i := 0; i < 4; i++ {
+		u, v, x, y := src[i].x, src[i].y, dst[i].x, dst[i].y
+		a[2*i] = [9]float64{u, v, 1, 0, 0, 0, -u * x, -v * x, x}
+		a[2*i+1] = [9]float64{0, 0, 0, u, v, 1, -u * y, -v * y, y}
+	}
+	for c := 0; c < 8; c++ {
+		p := c
+		for r := c + 1; r < 8; r++ {
+			if math.Abs(a[r][c]) > math.Abs(a[p][c]) {
+				p = r
+			}
+		}
+		if math.Abs(a[p][c]) < 1e-12 {
+			return nil, false
+		}
+		a[c], a[p] = a[p], a[c]
+		for r := 0; r < 8; r++ {
+			if r == c {
+				continue
+			}
+			f := a[r][c] / a[c][c]
+			for k := c; k < 9; k++ {
+				a[r][k] -= f * a[c][k]
+			}
+		}
+	}
+	h = new(transform)
+	for c := 0; c < 8; c++ {
+		h[c] = a[c][8] / a[c][c]
+	}
+	h[8] = 1
+	return h, true
+}
+
+// findAlignment looks for the bottom right alignment square of a
+// dim×dim code with the given position squares, returning its center.
+func (b *bitmap) findAlignment(tl, tr, bl *finder, dim int) (point, bool) {
+	ex := tr.sub(tl.point).mul(1 / float64(dim-7))
+	ey := bl.sub(tl.point).mul(1 / float64(dim-7))
+	want := tl.add(ex.mul(float64(dim) - 10)).add(ey.mul(float64(dim) - 10))
+	m := math.Max(math.Hypot(ex.x, ex.y), math.Hypot(ey.x, ey.y))
+
+	score := func(p point) int {
+		n := 0
+		for dv := -2; dv <= 2; dv++ {
+			for du := -2; du <= 2; du++ {
+				q := p.add(ex.mul(float64(du))).add(ey.mul(float64(dv)))
+				ring := du == -1 || du == 1 || dv == -1 || dv == 1
+				if du < -1 || du > 1 || dv < -1 || dv > 1 {
+					ring = false
+				}
+				if b.atf(q.x, q.y) != ring {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	for _, radius := range []float64{4, 8, 12} {
+		r := int(math.Ceil(radius * m))
+		cx, cy := int(want.x), int(want.y)
+		best := 0
+		var hits []point
+		for y := cy - r; y <= cy+r; y++ {
+			for x := cx - r; x <= cx+r; x++ {
+				p := point{float64(x) + 0.5, float64(y) + 0.5}
+				switch s := score(p); {
+				case s > best:
+					best, hits = s, append(hits[:0], p)
+				case s == best:
+					hits = append(hits, p)
+				}
+			}
+		}
+		if best < 23 {
+			continue
+		}
+		// Average the best positions near the one closest to
+		// the expected location, which form a plateau around
+		// the square's center.
+		near := hits[0]
+		for _, p := range hits {
+			if p.dist2(want) < near.dist2(want) {
+				near = p
+			}
+		}
+		var sum point
+		n := 0
+		for _, p := range hits {
+			if p.near(near, m) {
+				sum = sum.add(p)
+				n++
+			}
+		}
+		return sum.mul(1 / float64(n)), true
+	}
+	return point{}, false
+}
+
+// sample returns the dim×dim grid of modules of the code
+// with the given position squares, along with the transform
+// from module coordinates to image coordinates.
+func (b *bitmap) sample(tl, tr, bl *finder, dim int) ([][]bool, *transform, bool) {
+	d := float64(dim)
+	src := [4]point{{3.5, 3.5}, {d - 3.5, 3.5}, {3.5, d - 3.5}, {d - 3.5, d - 3.5}}
+	dst := [4]point{tl.point, tr.point, bl.point, tr.add(bl.point).sub(tl.point)}
+	if dim > 21 {
+		if p, ok := b.findAlignment(tl, tr, bl, dim); ok {
+			src[3], dst[3] = point{d - 6.5, d - 6.5}, p
+		}
+	}
+	h, ok := newTransform(src, dst)
+	if !ok {
+		return nil, nil, false
+	}
+	g := make([][]bool, dim)
+	for y := range g {
+		g[y] = make([]bool, dim)
+		for x := range g[y] {
+			p := h.apply(float64(x)+0.5, float64(y)+0.5)
+			g[y][x] = b.atf(p.x, p.y)
+		}
+	}
+	return g, h, true
+}
@@ -0,0 +1,325 @@
+// Copyright 2011 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qr
+
+// Fabrication output for QR codes: STL meshes for 3D printing
+// and DXF and SVG outlines for laser cutters.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"strconv"
+)
+
+// FabOptions describe the physical dimensions of a fabricated code.
+// All lengths are in millimetres.
+type FabOptions struct {
+	Module float64 // width of one QR pixel; 0 means 1mm
+	Base   float64 // thickness of the base plate; 0 means no plate
+	Relief float64 // height of black pixels above the plate; 0 means 1mm
+	Border int     // width of the plate margin around the code, in QR pixels
+}
+
+func (o *FabOptions) get() FabOptions {
+	var f FabOptions
+	if o != nil {
+		f = *o
+	}
+	if f.Module <= 0 {
+		f.Module = 1
+	}
+	if f.Relief <= 0 {
+		f.Relief = 1
+	}
+	if f.Base < 0 {
+		f.Base = 0
+	}
+	if f.Border < 0 {
+		f.Border = 0
+	}
+	return f
+}
+
+// rects returns a list of rectangles covering the black pixels,
+// merging horizontal runs and then stacking identical runs
+// from consecutive rows.
+func (c *Code) rects() []image.Rectangle {
+	used := make([]bool, c.Size*c.Size)
+	free := func(x, y int) bool {
+		return c.Black(x, y) && !used[y*c.Size+x]
+	}
+	var rs []image.Rectangle
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !free(x, y) {
+				continue
+			}
+			x1 := x + 1
+			for free(x1, y) {
+				x1++
+			}
+			y1 := y + 1
+		Down:
+			for ; y1 < c.Size; y1++ {
+				for i := x; i < x1; i++ {
+					if !free(i, y1) {
+						break Down
+					}
+				}
+			}
+			for j := y; j < y1; j++ {
+				for i := x; i < x1; i++ {
+					used[j*c.Size+i] = true
+				}
+			}
+			rs = append(rs, image.Rect(x, y, x1, y1))
+			x = x1 - 1
+		}
+	}
+	return rs
+}
+
+// contours returns the outlines of the black regions of the code,
+// in pixel coordinates. Each outline is a closed polygon listed
+// without repeating its first point. Outer edges run clockwise
+// (with y pointing down) and the edges of holes counterclockwise,
+// so that black is always on the right.
+func (c *Code) contours() [][]image.Point {
+	// Collect unit edges with black on the right.
+	out := make(map[image.Point][]image.Point)
+	add := func(p, d image.Point) { out[p] = append(out[p], d) }
+	var starts []image.Point
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.Black(x, y) {
+				continue
+			}
+			if !c.Black(x, y-1) {
+				add(image.Pt(x, y), image.Pt(1, 0))
+				starts = append(starts, image.Pt(x, y))
+			}
+			if !c.Black(x+1, y) {
+				add(image.Pt(x+1, y), image.Pt(0, 1))
+			}
+			if !c.Black(x, y+1) {
+				add(image.Pt(x+1, y+1), image.Pt(-1, 0))
+			}
+			if !c.Black(x-1, y) {
+				add(image.Pt(x, y+1), image.Pt(0, -1))
+			}
+		}
+	}
+
+	// take removes and returns the edge leaving p that turns
+	// most sharply right from direction d, so that pixels
+	// touching only at a corner get separate outlines.
+	take := func(p, d image.Point) image.Point {
+		ds := out[p]
+		best := -1
+		for i, nd := range ds {
+			if best < 0 || turn(d, nd) > turn(d, ds[best]) {
+				best = i
+			}
+		}
+		nd := ds[best]
+		ds[best] = ds[len(ds)-1]
+		if len(ds) == 1 {
+			delete(out, p)
+		} else {
+			out[p] = ds[:len(ds)-1]
+		}
+		return nd
+	}
+
+	// Every outline includes a top edge, so tracing from
+	// the top edges in order finds them all.
+	var polys [][]image.Point
+	for _, start := range starts {
+		if !hasDir(out[start], image.Pt(1, 0)) {
+			continue
+		}
+		var poly []image.Point
+		p, d := start, image.Pt(0, -1)
+		for {
+			nd := take(p, d)
+			if nd != d {
+				poly = append(poly, p)
+			}
+			p, d = p.Add(nd), nd
+			if p == start {
+				break
+			}
+		}
+		if d == image.Pt(1, 0) {
+			// Arrived heading right: start is not a corner.
+			poly = poly[1:]
+		}
+		polys = append(polys, poly)
+	}
+	return polys
+}
+
+// turn scores the turn from direction d to nd:
+// 2 for right, 1 for straight, 0 for left.
+func turn(d, nd image.Point) int {
+	switch cross := d.X*nd.Y - d.Y*nd.X; {
+	case cross > 0:
+		return 2
+	case cross == 0:
+		return 1
+	}
+	return 0
+}
+
+func hasDir(ds []image.Point, d image.Point) bool {
+	for _, x := range ds {
+		if x == d {
+			return true
+		}
+	}
+	return false
+}
+
+// STL returns a binary STL mesh of the code: a base plate with
+// the black pixels raised above it. Runs of black pixels are
+// merged into boxes to keep the triangle count low.
+// The mesh is laid out with the code readable from above
+// (looking down the z axis), with the lower left corner of
+// the plate at the origin.
+func (c *Code) STL(opt *FabOptions) []byte {
+	o := opt.get()
+	width := float64(c.Size+2*o.Border) * o.Module
+	var tris [][4][3]float32
+	box := func(x0, y0, z0, x1, y1, z1 float64) {
+		v := func(x, y, z float64) [3]float32 {
+			return [3]float32{float32(x), float32(y), float32(z)}
+		}
+		quad := func(n, a, b, c, d [3]float32) {
+			tris = append(tris, [4][3]float32{n, a, b, c}, [4][3]float32{n, a, c, d})
+		}
+		quad(v(0, 0, -1), v(x0, y0, z0), v(x0, y1, z0), v(x1, y1, z0), v(x1, y0, z0))
+		quad(v(0, 0, 1), v(x0, y0, z1), v(x1, y0, z1), v(x1, y1, z1), v(x0, y1, z1))
+		quad(v(0, -1, 0), v(x0, y0, z0), v(x1, y0, z0), v(x1, y0, z1), v(x0, y0, z1))
+		quad(v(0, 1, 0), v(x0, y1, z0), v(x0, y1, z1), v(x1, y1, z1), v(x1, y1, z0))
+		quad(v(-1, 0, 0), v(x0, y0, z0), v(x0, y0, z1), v(x0, y1, z1), v(x0, y1, z0))
+		quad(v(1, 0, 0), v(x1, y0, z0), v(x1, y1, z0), v(x1, y1, z1), v(x1, y0, z1))
+	}
+	if o.Base > 0 {
+		box(0, 0, 0, width, width, o.Base)
+	}
+	for _, r := range c.rects() {
+		x0 := float64(r.Min.X+o.Border) * o.Module
+		x1 := float64(r.Max.X+o.Border) * o.Module
+		y0 := width - float64(r.Max.Y+o.Border)*o.Module
+		y1 := width - float64(r.Min.Y+o.Border)*o.Module
+		box(x0, y0, o.Base, x1, y1, o.Base+o.Relief)
+	}
+
+	var b bytes.Buffer
+	var hdr [80]byte
+	copy(hdr[:], "QR code STL rsc.io/qr")
+	b.Write(hdr[:])
+	binary.Write(&b, binary.LittleEndian, uint32(len(tris)))
+	for _, t := range tris {
+		binary.Write(&b, binary.LittleEndian, t)
+		b.Write([]byte{0, 0}) // attribute byte count
+	}
+	return b.Bytes()
+}
+
+// DXF returns an AutoCAD R12 DXF drawing of the outlines of the
+// code's black regions, for laser cutting. Dimensions are in
+// millimetres, with y pointing up and the lower left corner of
+// the plate at the origin. The outlines are on layer CODE, raised
+// to the top of the plate and extruded by the relief height.
+// If the plate has a border or thickness, its outline is on layer PLATE.
+func (c *Code) DXF(opt *FabOptions) []byte {
+	o := opt.get()
+	width := float64(c.Size+2*o.Border) * o.Module
+	var b bytes.Buffer
+	pair := func(code int, val string) { fmt.Fprintf(&b, "%d\n%s\n", code, val) }
+	num := func(code int, f float64) { pair(code, strconv.FormatFloat(f, 'f', -1, 64)) }
+	polyline := func(layer string, elev, thick float64, pts [][2]float64) {
+		pair(0, "POLYLINE")
+		pair(8, layer)
+		pair(66, "1")
+		num(10, 0)
+		num(20, 0)
+		num(30, elev)
+		num(39, thick)
+		pair(70, "1") // closed
+		for _, p := range pts {
+			pair(0, "VERTEX")
+			pair(8, layer)
+			num(10, p[0])
+			num(20, p[1])
+			num(30, elev)
+		}
+		pair(0, "SEQEND")
+		pair(8, layer)
+	}
+
+	pair(0, "SECTION")
+	pair(2, "HEADER")
+	pair(9, "$INSUNITS")
+	pair(70, "4") // millimetres
+	pair(0, "ENDSEC")
+	pair(0, "SECTION")
+	pair(2, "ENTITIES")
+	if o.Base > 0 || o.Border > 0 {
+		polyline("PLATE", 0, o.Base, [][2]float64{{0, 0}, {width, 0}, {width, width}, {0, width}})
+	}
+	for _, poly := range c.contours() {
+		pts := make([][2]float64, len(poly))
+		for i, p := range poly {
+			pts[i] = [2]float64{float64(p.X+o.Border) * o.Module, width - float64(p.Y+o.Border)*o.Module}
+		}
+		polyline("CODE", o.Base, o.Relief, pts)
+	}
+	pair(0, "ENDSEC")
+	pair(0, "EOF")
+	return b.Bytes()
+}
+
+// svgPath returns SVG path data tracing the outlines of the code's
+// black regions, with each pixel one unit wide and the given offset
+// added to every point.
+func (c *Code) svgPath(off int) string {
+	var b bytes.Buffer
+	for _, poly := range c.contours() {
+		for i, p := range poly {
+			switch {
+			case i == 0:
+				fmt.Fprintf(&b, "M%d %d", p.X+off, p.Y+off)
+			case p.Y == poly[i-1].Y:
+				fmt.Fprintf(&b, "H%d", p.X+off)
+			default:
+				fmt.Fprintf(&b, "V%d", p.Y+off)
+			}
+		}
+		b.WriteString("Z")
+	}
+	return b.String()
+}
+
+// OutlineSVG returns an SVG drawing of the outlines of the code's
+// black regions, for laser cutting, sized in millimetres.
+// If the plate has a border, its outline is drawn as a separate
+// unfilled path.
+func (c *Code) OutlineSVG(opt *FabOptions) []byte {
+	o := opt.get()
+	n := c.Size + 2*o.Border
+	width := strconv.FormatFloat(float64(n)*o.Module, 'f', -1, 64)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%smm\" height=\"%smm\" viewBox=\"0 0 %d %d\">\n", width, width, n, n)
+	if o.Border > 0 {
+		fmt.Fprintf(&b, "<path d=\"M0 0H%dV%dH0Z\" fill=\"none\" stroke=\"black\" stroke-width=\"0.01\"/>\n", n, n)
+	}
+	fmt.Fprintf(&b, "<path d=\"%s\" fill=\"black\"/>\n", c.svgPath(o.Border))
+	fmt.Fprintf(&b, "</svg>\n")
+	return b.Bytes()
+}
@@ -0,0 +1,170 @@
+// Copyright 2011 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"image"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRects(t *testing.T) {
+	c, err := Encode("hello, world", Q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cover := make(map[image.Point]int)
+	for _, r := range c.rects() {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				cover[image.Pt(x, y)]++
+			}
+		}
+	}
+	black := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			n := cover[image.Pt(x, y)]
+			if c.Black(x, y) {
+				black++
+			}
+			if c.Black(x, y) && n != 1 || !c.Black(x, y) && n != 0 {
+				t.Errorf("pixel %d,%d covered %d times (black=%v)", x, y, n, c.Black(x, y))
+			}
+		}
+	}
+	if n := len(c.rects()); n*2 > black {
+		t.Errorf("%d rects for %d black pixels; merging is not working", n, black)
+	}
+}
+
+func TestContours(t *testing.T) {
+	c, err := Encode("hello, world", H)
+	if err != nil {
+		t.Fatal(err)
+	}
+	polys := c.contours()
+	for _, poly := range polys {
+		for i, p := range poly {
+			prev := poly[(i+len(poly)-1)%len(poly)]
+			next := poly[(i+1)%len(poly)]
+			if (prev.X == p.X) == (next.X == p.X) {
+				t.Errorf("point %v is not a corner: %v %v %v", p, prev, p, next)
+			}
+		}
+	}
+
+	// Compute winding number at each pixel center
+	// by casting a ray to the right.
+	nbad := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			w := 0
+			for _, poly := range polys {
+				for i, p := range poly {
+					q := poly[(i+1)%len(poly)]
+					if p.X != q.X || p.X <= x {
+						continue
+					}
+					if p.Y <= y && y < q.Y {
+						w++
+					} else if q.Y <= y && y < p.Y {
+						w--
+					}
+				}
+			}
+			if (w != 0) != c.Black(x, y) || w < 0 || w > 1 {
+				t.Errorf("pixel %d,%d has winding %d, black=%v", x, y, w, c.Black(x, y))
+				if nbad++; nbad >= 20 {
+					t.Fatalf("too many bad pixels")
+				}
+			}
+		}
+	}
+}
+
+func TestSTL(t *testing.T) {
+	c, err := Encode("hello, world", L)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt := &FabOptions{Module: 2, Base: 3, Relief: 1.5, Border: 2}
+	stl := c.STL(opt)
+	n := binary.LittleEndian.Uint32(stl[80:])
+	if want := 12 * (1 + len(c.rects())); int(n) != want {
+		t.Errorf("STL has %d triangles, want %d", n, want)
+	}
+	if len(stl) != 84+50*int(n) {
+		t.Fatalf("STL is %d bytes, want %d", len(stl), 84+50*n)
+	}
+	r := bytes.NewReader(stl[84:])
+	for i := 0; i < int(n); i++ {
+		var tri [4][3]float32
+		var attr uint16
+		binary.Read(r, binary.LittleEndian, &tri)
+		binary.Read(r, binary.LittleEndian, &attr)
+		// Vertices must be counterclockwise around the normal.
+		var u, v [3]float32
+		for k := 0; k < 3; k++ {
+			u[k] = tri[2][k] - tri[1][k]
+			v[k] = tri[3][k] - tri[1][k]
+		}
+		cross := [3]float32{u[1]*v[2] - u[2]*v[1], u[2]*v[0] - u[0]*v[2], u[0]*v[1] - u[1]*v[0]}
+		if dot := cross[0]*tri[0][0] + cross[1]*tri[0][1] + cross[2]*tri[0][2]; dot <= 0 {
+			t.Errorf("triangle %d: %v is clockwise", i, tri)
+		}
+		for _, p := range tri[1:] {
+			if p[0] < 0 || p[0] > 50 || p[1] < 0 || p[1] > 50 || p[2] < 0 || p[2] > 4.5 {
+				t.Errorf("triangle %d: vertex %v out of bounds", i, p)
+			}
+		}
+	}
+}
+
+func TestDXF(t *testing.T) {
+	c, err := Encode("hello, world", L)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dxf := string(c.DXF(&FabOptions{Border: 1}))
+	if !strings.HasSuffix(dxf, "0\nENDSEC\n0\nEOF\n") {
+		t.Errorf("DXF does not end with EOF")
+	}
+	if n, want := strings.Count(dxf, "0\nPOLYLINE\n"), 1+len(c.contours()); n != want {
+		t.Errorf("DXF has %d polylines, want %d", n, want)
+	}
+	if n := strings.Count(dxf, "0\nSEQEND\n"); n != 1+len(c.contours()) {
+		t.Errorf("DXF has %d SEQENDs, want %d", n, 1+len(c.contours()))
+	}
+}
+
+func TestOutlineSVG(t *testing.T) {
+	c, err := Encode("hello, world", L)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svg := c.OutlineSVG(&FabOptions{Module: 0.5, Border: 4})
+	d := xml.NewDecoder(bytes.NewReader(svg))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid SVG: %v\n%s", err, svg)
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == "svg" {
+			for _, a := range se.Attr {
+				if a.Name.Local == "width" && a.Value != "14.5mm" {
+					t.Errorf("width = %q, want 14.5mm", a.Value)
+				}
+			}
+		}
+	}
+}
@@ -5,7 +5,10 @@
 // Package gf256 implements arithmetic over the Galois Field GF(256).
 package gf256 // import "rsc.io/qr/gf256"
 
-import "strconv"
+import (
+	"errors"
+	"strconv"
+)
 
 // A Field represents an instance of GF(256) defined by a specific polynomial.
 type Field struct {
@@ -239,3 +242,159 @@ func (rs *RSEncoder) ECC(data []byte, check []byte) {
 	copy(check, p[len(data):])
 	rs.p = p
 }
+
+// An RSDecoder implements Reed-Solomon error correction
+// over a given field using a given number of error correction bytes.
+// It corrects codewords produced by an RSEncoder with the same
+// parameters: data bytes followed by check bytes.
+type RSDecoder struct {
+	f *Field
+	c int
+}
+
+// NewRSDecoder returns a new Reed-Solomon decoder
+// over the given field and number of error correction bytes.
+func NewRSDecoder(f *Field, c int) *RSDecoder {
+	return &RSDecoder{f: f, c: c}
+}
+
+// ErrTooManyErrors is returned by RSDecoder.Correct
+// when a codeword has more errors than it can correct.
+var ErrTooManyErrors = errors.New("gf256: too many errors")
+
+// Correct corrects the codeword p, data bytes followed by check bytes,
+// in place. It returns the number of bytes corrected.
+// A codeword with c check bytes can be corrected if at most c/2
+// of its bytes are wrong; with more errors, Correct returns
+// ErrTooManyErrors and leaves p unchanged, although it may also
+// miscorrect p into a different valid codeword.
+func (rs *RSDecoder) Correct(p []byte) (int, error) {
+	f := rs.f
+	n := len(p)
+	if n > 255 || n < rs.c {
+		return 0, errors.New("gf256: invalid codeword length")
+	}
+
+	// Syndromes: s[j] = p(α^j), where p[0] is the
+	// coefficient of the highest power of x.
+	s := make([]byte, rs.c)
+	nonzero := false
+	for j := range s {
+		s[j] = f.eval(p, f.Exp(j))
+		if s[j] != 0 {
+			nonzero = true
+		}
+	}
+	if !nonzero {
+		return 0, nil
+	}
+
+	// Berlekamp-Massey: find the error locator polynomial λ,
+	// stored lowest coefficient first.
+	λ := []byte{1}
+	b := []byte{1}
+	nerr, m, bd := 0, 1, byte(1)
+	for k := 0; k < rs.c; k++ {
+		d := s[k]
+		for i := 1; i <= nerr && i < len(λ); i++ {
+			d ^= f.Mul(λ[i], s[k-i])
+		}
+		if d == 0 {
+			m++
+			continue
+		}
+		// next = λ - d/bd x^m b
+		coef := f.Mul(d, f.Inv(bd))
+		size := len(b) + m
+		if size < len(λ) {
+			size = len(λ)
+		}
+		next := make([]byte, size)
+		copy(next, λ)
+		for i, v := range b {
+			next[i+m] ^= f.Mul(coef, v)
+		}
+		if 2*nerr <= k {
+			nerr = k + 1 - nerr
+			b, bd, m = λ, d, 1
+		} else {
+			m++
+		}
+		λ = next
+	}
+	for len(λ) > 1 && λ[len(λ)-1] == 0 {
+		λ = λ[:len(λ)-1]
+	}
+	if len(λ)-1 != nerr || 2*nerr > rs.c {
+		return 0, ErrTooManyErrors
+	}
+
+	// Chien search: byte i is wrong if λ(X^-1) = 0, where X = α^(n-1-i).
+	var pos []int
+	for i := 0; i < n; i++ {
+		xinv := f.Exp(255 - (n - 1 - i))
+		if f.evalLow(λ, xinv) == 0 {
+			pos = append(pos, i)
+		}
+	}
+	if len(pos) != nerr {
+		return 0, ErrTooManyErrors
+	}
+
+	// Forney: the error value at X is X Ω(X^-1) / λ'(X^-1),
+	// where Ω = s λ mod x^c.
+	ω := make([]byte, rs.c)
+	for i, v := range λ {
+		for j := 0; i+j < rs.c; j++ {
+			ω[i+j] ^= f.Mul(v, s[j])
+		}
+	}
+	// In characteristic 2, λ' keeps only the odd terms of λ.
+	dλ := make([]byte, len(λ))
+	for i := 1; i < len(λ); i += 2 {
+		dλ[i-1] = λ[i]
+	}
+	fix := make([]byte, len(pos))
+	for k, i := range pos {
+		x := f.Exp(n - 1 - i)
+		xinv := f.Inv(x)
+		den := f.evalLow(dλ, xinv)
+		if den == 0 {
+			return 0, ErrTooManyErrors
+		}
+		fix[k] = f.Mul(x, f.Mul(f.evalLow(ω, xinv), f.Inv(den)))
+	}
+
+	// Apply the corrections and check the result.
+	q := append([]byte(nil), p...)
+	for k, i := range pos {
+		q[i] ^= fix[k]
+	}
+	for j := 0; j < rs.c; j++ {
+		if f.eval(q, f.Exp(j)) != 0 {
+			return 0, ErrTooManyErrors
+		}
+	}
+	copy(p, q)
+	return len(pos), nil
+}
+
+// eval returns the value of the polynomial p at x,
+// where p[0] is the coefficient of the highest power of x.
+func (f *Field) eval(p []byte, x byte) byte {
+	var v byte
+	for _, c := range p {
+		v = f.Mul(v, x) ^ c
+	}
+	return v
+}
+
+// evalLow returns the value of the polynomial p at x,
+// where p[0] is the coefficient of the lowest power of x.
+func (f *Field) evalLow(p []byte, x byte) byte {
+	var v byte
+	for i := len(p) - 1; i >= 0; i-- {
+		v = f.Mul(v, x) ^ p[i]
+	}
+	return v
+}
@@ -7,6 +7,7 @@ package gf256
 import (
 	"bytes"
 	"fmt"
+	"math/rand"
 	"testing"
 )
 
@@ -192,3 +193,52 @@ func generates(α, poly int) bool {
 	}
 	return true
 }
+
+func TestRSDecoder(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, nc := range []int{2, 7, 10, 22, 30} {
+		enc := NewRSEncoder(f, nc)
+		dec := NewRSDecoder(f, nc)
+		for iter := 0; iter < 200; iter++ {
+			nd := 1 + rnd.Intn(100)
+			p := make([]byte, nd+nc)
+			rnd.Read(p[:nd])
+			enc.ECC(p[:nd], p[nd:])
+			orig := append([]byte(nil), p...)
+
+			// Up to nc/2 errors are always corrected.
+			nerr := rnd.Intn(nc/2 + 1)
+			for _, i := range rnd.Perm(len(p))[:nerr] {
+				p[i] ^= byte(1 + rnd.Intn(255))
+			}
+			n, err := dec.Correct(p)
+			if err != nil || n != nerr || !bytes.Equal(p, orig) {
+				t.Fatalf("nc=%d nd=%d: Correct with %d errors = %d, %v; codeword ok=%v", nc, nd, nerr, n, err, bytes.Equal(p, orig))
+			}
+		}
+	}
+
+	// Too many errors are detected (or, rarely, miscorrected
+	// into a different codeword, never a non-codeword).
+	enc := NewRSEncoder(f, 10)
+	dec := NewRSDecoder(f, 10)
+	detected := 0
+	for iter := 0; iter < 100; iter++ {
+		p := make([]byte, 30)
+		rnd.Read(p[:20])
+		enc.ECC(p[:20], p[20:])
+		for _, i := range rnd.Perm(len(p))[:8] {
+			p[i] ^= byte(1 + rnd.Intn(255))
+		}
+		bad := append([]byte(nil), p...)
+		if _, err := dec.Correct(p); err != nil {
+			detected++
+			if !bytes.Equal(p, bad) {
+				t.Fatalf("failed Correct modified codeword")
+			}
+		}
+	}
+	if detected < 90 {
+		t.Errorf("detected only %d of 100 uncorrectable codewords", detected)
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package layout arranges QR codes on printed pages.
+//
+// A Sheet places many codes, each with an optional caption, in a grid
+// of labels, as on standard label stock, starting a new page whenever
+// one fills up. Tiles splits a single code too large for one page
+// across several pages, with registration marks for assembling them.
+//
+// Both write either a multi-page PDF or one SVG document per page.
+// All lengths are in millimeters, measured from the top left corner
+// of the page, except font sizes, which are in points.
+package layout // import "rsc.io/qr/layout"
+
+import (
+	"fmt"
+	"math"
+
+	"rsc.io/qr"
+)
+
+// Paper sizes, in millimeters.
+const (
+	A4Width, A4Height         = 210, 297
+	LetterWidth, LetterHeight = 215.9, 279.4
+)
+
+// A Label is a code to place on a Sheet, with an optional caption
+// printed below it.
+type Label struct {
+	Code    *qr.Code
+	Caption string
+}
+
+// A Sheet describes a page of labels in a grid.
+type Sheet struct {
+	PageWidth, PageHeight float64 // page size; zero means A4
+
+	// Top and Left give the position of the top left label.
+	Top, Left float64
+
+	// Columns and Rows give the number of labels across
+	// and down each page. Zero means 1.
+	Columns, Rows int
+
+	// LabelWidth and LabelHeight give the size of each label.
+	// Zero means to divide the page evenly, leaving the same
+	// margins on the right and bottom as on the left and top.
+	LabelWidth, LabelHeight float64
+
+	// GutterX and GutterY give the space between
+	// neighboring labels across and down.
+	GutterX, GutterY float64
+
+	Padding  float64 // space inside each label around its contents
+	Border   int     // quiet zone around each code, in modules
+	FontSize float64 // caption size in points; zero means 8
+	Outline  bool    // outline each label, for proofs on plain paper
+}
+
+// Label stock layouts.
+var (
+	// AveryL7160 is A4 stock with 21 labels, 63.5 × 38.1 mm.
+	AveryL7160 = Sheet{
+		PageWidth: A4Width, PageHeight: A4Height,
+		Top: 15.15, Left: 7.25,
+		Columns: 3, Rows: 7,
+		LabelWidth: 63.5, LabelHeight: 38.1,
+		GutterX: 2.5,
+		Padding: 2, Border: 4,
+	}
+
+	// Avery5160 is US Letter stock with 30 labels, 2⅝ × 1 in.
+	Avery5160 = Sheet{
+		PageWidth: LetterWidth, PageHeight: LetterHeight,
+		Top: 12.7, Left: 4.7625,
+		Columns: 3, Rows: 10,
+		LabelWidth: 66.675, LabelHeight: 25.4,
+		GutterX: 3.175,
+		Padding: 1.5, Border: 4,
+	}
+)
+
+// ptPerMM is the number of PDF points (1/72 in) per millimeter.
+const ptPerMM = 72 / 25.4
+
+// A page is a page of drawing operations.
+type page struct {
+	w, h float64
+	ops  []op
+}
+
+type opKind int
+
+const (
+	opRect opKind = iota // filled black rectangle
+	opLine               // line from (x, y) to (x+w, y+h)
+	opText               // text centered at x with baseline y
+	opBox                // outlined rectangle
+)
+
+// An op is a single drawing operation.
+type op struct {
+	kind       opKind
+	x, y, w, h float64
+	text       string
+	size       float64 // font size in points
+}
+
+func (p *page) rect(x, y, w, h float64) {
+	p.ops = append(p.ops, op{kind: opRect, x: x, y: y, w: w, h: h})
+}
+
+func (p *page) line(x0, y0, x1, y1 float64) {
+	p.ops = append(p.ops, op{kind: opLine, x: x0, y: y0, w: x1 - x0, h: y1 - y0})
+}
+
+func (p *page) box(x, y, w, h float64) {
+	p.ops = append(p.ops, op{kind: opBox, x: x, y: y, w: w, h: h})
+}
+
+func (p *page) text(x, y float64, s string, size float64) {
+	p.ops = append(p.ops, op{kind: opText, x: x, y: y, text: s, size: size})
+}
+
+// code draws the part of c inside clip, with a border-module
+// quiet zone, as a square of side size at (x, y).
+// Each row of black modules is drawn as rectangles
+// covering its horizontal runs.
+func (p *page) code(c *qr.Code, border int, x, y, size float64, clip rect) {
+	n := c.Size + 2*border
+	m := size / float64(n)
+	for row := 0; row < c.Size; row++ {
+		for col := 0; col < c.Size; {
+			if !c.Black(col, row) {
+				col++
+				continue
+			}
+			c0 := col
+			for col < c.Size && c.Black(col, row) {
+				col++
+			}
+			r := rect{
+				x + float64(c0+border)*m,
+				y + float64(row+border)*m,
+				float64(col-c0) * m,
+				m,
+			}
+			if r, ok := r.intersect(clip); ok {
+				p.rect(r.x, r.y, r.w, r.h)
+			}
+		}
+	}
+}
+
+// A rect is a rectangle with top left corner (x, y).
+type rect struct {
+	x, y, w, h float64
+}
+
+var everywhere = rect{-1e9, -1e9, 2e9, 2e9}
+
+func (r rect) intersect(s rect) (rect, bool) {
+	x0, y0 := math.Max(r.x, s.x), math.Max(r.y, s.y)
+	x1, y1 := math.Min(r.x+r.w, s.x+s.w), math.Min(r.y+r.h, s.y+s.h)
+	if x1 <= x0 || y1 <= y0 {
+		return rect{}, false
+	}
+	return rect{x0, y0, x1 - x0, y1 - y0}, true
+}
+
+// normalize returns s with defaults filled in.
+func (s *Sheet) normalize() (Sheet, error) {
+	t := *s
+	if t.PageWidth == 0 && t.PageHeight == 0 {
+		t.PageWidth, t.PageHeight = A4Width, A4Height
+	}
+	if t.Columns == 0 {
+		t.Columns = 1
+	}
+	if t.Rows == 0 {
+		t.Rows = 1
+	}
+	if t.FontSize == 0 {
+		t.FontSize = 8
+	}
+	if t.LabelWidth == 0 {
+		t.LabelWidth = (t.PageWidth - 2*t.Left - float64(t.Columns-1)*t.GutterX) / float64(t.Columns)
+	}
+	if t.LabelHeight == 0 {
+		t.LabelHeight = (t.PageHeight - 2*t.Top - float64(t.Rows-1)*t.GutterY) / float64(t.Rows)
+	}
+	switch {
+	case t.PageWidth <= 0 || t.PageHeight <= 0:
+		return t, fmt.Errorf("layout: invalid page size %g × %g", t.PageWidth, t.PageHeight)
+	case t.Columns < 0 || t.Rows < 0:
+		return t, fmt.Errorf("layout: invalid grid %d × %d", t.Columns, t.Rows)
+	case t.Border < 0 || t.Padding < 0 || t.FontSize < 0:
+		return t, fmt.Errorf("layout: invalid border, padding, or font size")
+	case t.LabelWidth <= 2*t.Padding || t.LabelHeight <= 2*t.Padding:
+		return t, fmt.Errorf("layout: labels too small")
+	case t.Left+float64(t.Columns)*t.LabelWidth+float64(t.Columns-1)*t.GutterX > t.PageWidth+0.01,
+		t.Top+float64(t.Rows)*t.LabelHeight+float64(t.Rows-1)*t.GutterY > t.PageHeight+0.01:
+		return t, fmt.Errorf("layout: labels do not fit on page")
+	}
+	return t, nil
+}
+
+// pages lays out the labels.
+func (s *Sheet) pages(labels []Label) ([]*page, error) {
+	t, err := s.normalize()
+	if err != nil {
+		return nil, err
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("layout: no labels")
+	}
+	perPage := t.Columns * t.Rows
+	var pages []*page
+	for i, l := range labels {
+		if l.Code == nil {
+			return nil, fmt.Errorf("layout: label %d has no code", i)
+		}
+		if i%perPage == 0 {
+			pages = append(pages, &page{w: t.PageWidth, h: t.PageHeight})
+		}
+		p := pages[len(pages)-1]
+		col, row := i%perPage%t.Columns, i%perPage/t.Columns
+		x := t.Left + float64(col)*(t.LabelWidth+t.GutterX)
+		y := t.Top + float64(row)*(t.LabelHeight+t.GutterY)
+		if t.Outline {
+			p.box(x, y, t.LabelWidth, t.LabelHeight)
+		}
+		t.label(p, l, x, y)
+	}
+	return pages, nil
+}
+
+// label draws l in the label with top left corner (x, y).
+func (s *Sheet) label(p *page, l Label, x, y float64) {
+	w, h := s.LabelWidth-2*s.Padding, s.LabelHeight-2*s.Padding
+	x, y = x+s.Padding, y+s.Padding
+
+	// Shrink the caption to fit the label's width, if needed,
+	// and leave room for it under the code.
+	size := s.FontSize
+	capHeight := 0.0
+	if l.Caption != "" {
+		if tw := textWidth(l.Caption, size) / ptPerMM; tw > w {
+			size *= w / tw
+		}
+		capHeight = 1.2 * size / ptPerMM
+	}
+	side := math.Min(w, h-capHeight)
+	if side <= 0 {
+		return
+	}
+	// The quiet zone already separates the code from the caption.
+	cx := x + (w-side)/2
+	p.code(l.Code, s.Border, cx, y, side, everywhere)
+	if l.Caption != "" {
+		p.text(x+w/2, y+side+0.9*size/ptPerMM, l.Caption, size)
+	}
+}
+
+// PDF returns a PDF document showing the labels on as many pages as needed.
+// There must be at least one label.
+func (s *Sheet) PDF(labels []Label) ([]byte, error) {
+	pages, err := s.pages(labels)
+	if err != nil {
+		return nil, err
+	}
+	return writePDF(pages), nil
+}
+
+// SVG returns an SVG document for each page of labels.
+// There must be at least one label.
+func (s *Sheet) SVG(labels []Label) ([][]byte, error) {
+	pages, err := s.pages(labels)
+	if err != nil {
+		return nil, err
+	}
+	var docs [][]byte
+	for _, p := range pages {
+		docs = append(docs, writeSVG(p))
+	}
+	return docs, nil
+}
@@ -0,0 +1,297 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package layout
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"rsc.io/qr"
+)
+
+func code(t *testing.T, text string) *qr.Code {
+	t.Helper()
+	c, err := qr.Encode(text, qr.M)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func labels(t *testing.T, n int) []Label {
+	var ls []Label
+	for i := 0; i < n; i++ {
+		ls = append(ls, Label{code(t, fmt.Sprint("item ", i)), fmt.Sprint("Item #", i)})
+	}
+	return ls
+}
+
+// blackArea returns the total area of the black rectangles on pages.
+func blackArea(pages []*page) float64 {
+	a := 0.0
+	for _, p := range pages {
+		for _, o := range p.ops {
+			if o.kind == opRect {
+				a += o.w * o.h
+			}
+		}
+	}
+	return a
+}
+
+func countBlack(c *qr.Code) int {
+	n := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.Black(x, y) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestSheet(t *testing.T) {
+	s, err := AveryL7160.normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls := labels(t, 50)
+	pages, err := s.pages(ls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 3 {
+		t.Fatalf("%d pages, want 3", len(pages))
+	}
+
+	// Every code and caption lies within its label.
+	for pi, p := range pages {
+		for _, o := range p.ops {
+			x, y := o.x, o.y
+			if o.kind == opRect {
+				x, y = o.x+o.w/2, o.y+o.h/2
+			}
+			col := math.Floor((x - s.Left) / (s.LabelWidth + s.GutterX))
+			row := math.Floor((y - s.Top) / (s.LabelHeight + s.GutterY))
+			lx := x - s.Left - col*(s.LabelWidth+s.GutterX)
+			ly := y - s.Top - row*(s.LabelHeight+s.GutterY)
+			if col < 0 || col >= 3 || row < 0 || row >= 7 || lx < s.Padding || lx > s.LabelWidth-s.Padding || ly < s.Padding || ly > s.LabelHeight-s.Padding {
+				t.Fatalf("page %d: op %+v outside labels", pi, o)
+			}
+		}
+	}
+
+	// The modules are square and all drawn.
+	want := 0.0
+	for _, l := range ls {
+		side := s.LabelHeight - 2*s.Padding - 1.2*s.FontSize/ptPerMM
+		m := side / float64(l.Code.Size+2*s.Border)
+		want += float64(countBlack(l.Code)) * m * m
+	}
+	if got := blackArea(pages); math.Abs(got-want) > 1e-6*want {
+		t.Errorf("black area %g, want %g", got, want)
+	}
+}
+
+func TestSheetErrors(t *testing.T) {
+	c := code(t, "x")
+	for _, s := range []Sheet{
+		{PageWidth: -1, PageHeight: 100},
+		{Columns: 3, LabelWidth: 100},
+		{Padding: 200},
+		{Border: -1},
+	} {
+		if _, err := s.PDF([]Label{{Code: c}}); err == nil {
+			t.Errorf("%+v: no error", s)
+		}
+	}
+	var s Sheet
+	if _, err := s.PDF([]Label{{Caption: "no code"}}); err == nil {
+		t.Errorf("label without code: no error")
+	}
+	if _, err := s.PDF(nil); err == nil {
+		t.Errorf("PDF with no labels: no error")
+	}
+	if _, err := s.SVG([]Label{}); err == nil {
+		t.Errorf("SVG with no labels: no error")
+	}
+}
+
+func TestCaptionFit(t *testing.T) {
+	s := Sheet{Columns: 4, Rows: 10, Padding: 1}
+	long := strings.Repeat("A very long caption ", 10)
+	pages, err := s.pages([]Label{{code(t, "x"), long}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0, _ := s.normalize()
+	for _, o := range pages[0].ops {
+		if o.kind == opText {
+			if w := textWidth(o.text, o.size) / ptPerMM; w > t0.LabelWidth-2*t0.Padding+1e-9 {
+				t.Errorf("caption width %g, label width %g", w, t0.LabelWidth)
+			}
+			if o.size >= 8 {
+				t.Errorf("caption not shrunk: size %g", o.size)
+			}
+		}
+	}
+}
+
+func TestTiles(t *testing.T) {
+	c := code(t, "https://example.com/poster")
+	for _, tt := range []struct {
+		tiles      Tiles
+		rows, cols int
+	}{
+		{Tiles{Size: 100, Border: 4}, 1, 1},
+		{Tiles{Size: 500, Border: 4}, 2, 3},
+		{Tiles{Size: 500, Border: 4, Overlap: 10}, 2, 3},
+		{Tiles{PageWidth: LetterWidth, PageHeight: LetterHeight, Margin: 10, Size: 1000}, 4, 6},
+	} {
+		pages, err := tt.tiles.pages(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pages) != tt.rows*tt.cols {
+			t.Errorf("%+v: %d pages, want %d×%d", tt.tiles, len(pages), tt.rows, tt.cols)
+			continue
+		}
+		m := tt.tiles.Size / float64(c.Size+2*tt.tiles.Border)
+		want := float64(countBlack(c)) * m * m
+		got := blackArea(pages)
+		if tt.tiles.Overlap == 0 && math.Abs(got-want) > 1e-6*want {
+			t.Errorf("%+v: black area %g, want %g", tt.tiles, got, want)
+		}
+		if tt.tiles.Overlap > 0 && got <= want {
+			t.Errorf("%+v: black area %g, want more than %g with overlap", tt.tiles, got, want)
+		}
+
+		// Everything drawn stays on the page; codes stay inside the margins.
+		margin := tt.tiles.Margin
+		if margin == 0 {
+			margin = 15
+		}
+		for _, p := range pages {
+			for _, o := range p.ops {
+				if o.x < 0 || o.y < 0 || o.x+o.w > p.w || o.y+o.h > p.h {
+					t.Fatalf("%+v: op %+v off page", tt.tiles, o)
+				}
+				if o.kind == opRect && (o.x < margin-1e-9 || o.y < margin-1e-9 || o.x+o.w > p.w-margin+1e-9 || o.y+o.h > p.h-margin+1e-9) {
+					t.Fatalf("%+v: rect %+v in margin", tt.tiles, o)
+				}
+			}
+		}
+	}
+
+	for _, tl := range []Tiles{{}, {Size: 100, Margin: 2}, {Size: 100, Overlap: 200}} {
+		if _, err := tl.PDF(c); err == nil {
+			t.Errorf("%+v: no error", tl)
+		}
+	}
+}
+
+var (
+	objRE    = regexp.MustCompile(`(?m)^(\d+) 0 obj$`)
+	xrefRE   = regexp.MustCompile(`(?m)^(\d{10}) 00000 n $`)
+	streamRE = regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`)
+)
+
+func TestPDF(t *testing.T) {
+	s := AveryL7160
+	s.Outline = true
+	data, err := s.PDF(append(labels(t, 22), Label{code(t, "x"), "(paren) \\ café 日本"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("bad PDF header or trailer")
+	}
+	if !bytes.Contains(data, []byte("/Count 2 ")) {
+		t.Errorf("PDF does not have 2 pages")
+	}
+
+	// The cross-reference table points at each object.
+	xref := xrefRE.FindAllSubmatch(data, -1)
+	objs := objRE.FindAllSubmatchIndex(data, -1)
+	if len(xref) != len(objs) || len(objs) != 3+2*2 {
+		t.Fatalf("%d xref entries, %d objects", len(xref), len(objs))
+	}
+	for i, x := range xref {
+		off, _ := strconv.Atoi(string(x[1]))
+		if off != objs[i][0] {
+			t.Errorf("xref %d: offset %d, object at %d", i+1, off, objs[i][0])
+		}
+	}
+	start := bytes.LastIndex(data, []byte("startxref\n"))
+	off, _ := strconv.Atoi(strings.Fields(string(data[start+10:]))[0])
+	if !bytes.HasPrefix(data[off:], []byte("xref\n")) {
+		t.Errorf("startxref does not point at xref table")
+	}
+
+	// The content streams inflate and draw the escaped caption.
+	var content bytes.Buffer
+	for _, m := range streamRE.FindAllSubmatch(data, -1) {
+		zr, err := zlib.NewReader(bytes.NewReader(m[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(&content, zr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !strings.Contains(content.String(), "(\\(paren\\) \\\\ caf\xe9 ??) Tj") {
+		t.Errorf("escaped caption not found in content")
+	}
+	if !strings.Contains(content.String(), " re S\n") {
+		t.Errorf("label outlines not found in content")
+	}
+}
+
+func TestSVG(t *testing.T) {
+	tl := Tiles{Size: 400, Border: 4}
+	docs, err := tl.SVG(code(t, "svg <&> poster"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 6 {
+		t.Fatalf("%d SVG pages, want 6", len(docs))
+	}
+	for i, doc := range docs {
+		d := xml.NewDecoder(bytes.NewReader(doc))
+		for {
+			_, err := d.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("page %d: invalid XML: %v", i, err)
+			}
+		}
+		if !bytes.Contains(doc, []byte(`width="210mm" height="297mm"`)) {
+			t.Errorf("page %d: not A4", i)
+		}
+		if !bytes.Contains(doc, []byte(fmt.Sprintf("row %d of 2, column %d of 3", i/3+1, i%3+1))) {
+			t.Errorf("page %d: missing tile caption", i)
+		}
+	}
+
+	s := Sheet{Columns: 2, Rows: 2}
+	docs, err = s.SVG([]Label{{code(t, "a"), "a < b & c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || !bytes.Contains(docs[0], []byte("a &lt; b &amp; c</text>")) {
+		t.Errorf("sheet SVG missing escaped caption")
+	}
+}
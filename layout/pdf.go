@@ -0,0 +1,146 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package layout
+
+// PDF writer for pages of labels and tiles.
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// writePDF returns a PDF document with the given pages.
+// Captions use the standard Helvetica font, which PDF
+// readers provide, so the document embeds no fonts.
+func writePDF(pages []*page) []byte {
+	var b bytes.Buffer
+	var offsets []int
+	obj := func(format string, args ...interface{}) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n", len(offsets))
+		fmt.Fprintf(&b, format, args...)
+		b.WriteString("\nendobj\n")
+	}
+
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1, 2, and 3 are the catalog, page tree, and font;
+	// each page i is then object 4+2i, with contents 5+2i.
+	var kids bytes.Buffer
+	for i := range pages {
+		fmt.Fprintf(&kids, "%d 0 R ", 4+2*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj("<< /Type /Pages /Kids [%s] /Count %d >>", bytes.TrimSpace(kids.Bytes()), len(pages))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	for i, p := range pages {
+		obj("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			num(p.w*ptPerMM), num(p.h*ptPerMM), 5+2*i)
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		zw.Write(pdfContent(p))
+		zw.Close()
+		obj("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", z.Len(), z.Bytes())
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return b.Bytes()
+}
+
+// pdfContent returns the content stream drawing p.
+// PDF coordinates are in points, with y increasing up the page.
+func pdfContent(p *page) []byte {
+	var b bytes.Buffer
+	x := func(v float64) string { return num(v * ptPerMM) }
+	y := func(v float64) string { return num((p.h - v) * ptPerMM) }
+	d := func(v float64) string { return num(v * ptPerMM) }
+
+	// Fill all the rectangles at once.
+	b.WriteString("0 g\n")
+	n := 0
+	for _, o := range p.ops {
+		if o.kind == opRect {
+			fmt.Fprintf(&b, "%s %s %s %s re\n", x(o.x), y(o.y+o.h), d(o.w), d(o.h))
+			n++
+		}
+	}
+	if n > 0 {
+		b.WriteString("f\n")
+	}
+	b.WriteString("0 G 0.25 w\n")
+	for _, o := range p.ops {
+		switch o.kind {
+		case opLine:
+			fmt.Fprintf(&b, "%s %s m %s %s l S\n", x(o.x), y(o.y), x(o.x+o.w), y(o.y+o.h))
+		case opBox:
+			fmt.Fprintf(&b, "%s %s %s %s re S\n", x(o.x), y(o.y+o.h), d(o.w), d(o.h))
+		case opText:
+			w := textWidth(o.text, o.size)
+			fmt.Fprintf(&b, "BT /F1 %s Tf %s %s Td (%s) Tj ET\n",
+				num(o.size), num(o.x*ptPerMM-w/2), y(o.y), pdfString(o.text))
+		}
+	}
+	return b.Bytes()
+}
+
+// num formats v for PDF, with at most three decimal places.
+func num(v float64) string {
+	v = math.Round(v*1000) / 1000
+	if v == 0 {
+		v = 0 // no -0
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// pdfString returns s, converted to WinAnsiEncoding, escaped
+// for use in a PDF string literal. Characters outside Latin-1
+// become question marks.
+func pdfString(s string) []byte {
+	var b []byte
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b = append(b, '\\', byte(r))
+		case ' ' <= r && r <= '~', 0xa0 <= r && r <= 0xff:
+			b = append(b, byte(r))
+		default:
+			b = append(b, '?')
+		}
+	}
+	return b
+}
+
+// textWidth returns the width of s in points,
+// printed in Helvetica of the given size.
+func textWidth(s string, size float64) float64 {
+	w := 0
+	for _, r := range s {
+		if ' ' <= r && r <= '~' {
+			w += helvetica[r-' ']
+		} else {
+			w += 556
+		}
+	}
+	return float64(w) * size / 1000
+}
+
+// helvetica holds the widths of the printable ASCII characters
+// in Helvetica, in thousandths of the font size.
+var helvetica = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 to ?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ to O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P to _
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` to o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p to ~
+}
@@ -0,0 +1,55 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package layout
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+// writeSVG returns an SVG document drawing p,
+// sized in millimeters with a viewBox in millimeters.
+func writeSVG(p *page) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%smm\" height=\"%smm\" viewBox=\"0 0 %s %s\">\n",
+		num(p.w), num(p.h), num(p.w), num(p.h))
+	fmt.Fprintf(&b, "<rect width=\"%s\" height=\"%s\" fill=\"white\"/>\n", num(p.w), num(p.h))
+
+	// Draw all the rectangles as one path.
+	n := 0
+	for _, o := range p.ops {
+		if o.kind != opRect {
+			continue
+		}
+		if n == 0 {
+			b.WriteString("<path shape-rendering=\"crispEdges\" fill=\"black\" d=\"")
+		}
+		fmt.Fprintf(&b, "M%s %sh%sv%sh%sz", num(o.x), num(o.y), num(o.w), num(o.h), num(-o.w))
+		n++
+	}
+	if n > 0 {
+		b.WriteString("\"/>\n")
+	}
+
+	const stroke = 0.25 / ptPerMM // 0.25pt, as in the PDF
+	for _, o := range p.ops {
+		switch o.kind {
+		case opLine:
+			fmt.Fprintf(&b, "<line x1=\"%s\" y1=\"%s\" x2=\"%s\" y2=\"%s\" stroke=\"black\" stroke-width=\"%s\"/>\n",
+				num(o.x), num(o.y), num(o.x+o.w), num(o.y+o.h), num(stroke))
+		case opBox:
+			fmt.Fprintf(&b, "<rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" fill=\"none\" stroke=\"black\" stroke-width=\"%s\"/>\n",
+				num(o.x), num(o.y), num(o.w), num(o.h), num(stroke))
+		case opText:
+			fmt.Fprintf(&b, "<text x=\"%s\" y=\"%s\" font-family=\"Helvetica, Arial, sans-serif\" font-size=\"%s\" text-anchor=\"middle\">",
+				num(o.x), num(o.y), num(o.size/ptPerMM))
+			xml.EscapeText(&b, []byte(o.text))
+			b.WriteString("</text>\n")
+		}
+	}
+	b.WriteString("</svg>\n")
+	return b.Bytes()
+}
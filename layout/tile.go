@@ -0,0 +1,137 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package layout
+
+import (
+	"fmt"
+	"math"
+
+	"rsc.io/qr"
+)
+
+// Tiles describes how to split a code too large for one page
+// across several pages, as for a poster.
+//
+// Each page prints one tile of the code inside its margins.
+// Corner marks in the margins show where each tile ends, and
+// cross marks at the middle of each edge line up with the
+// matching marks on the neighboring tiles. Trimming each page
+// at its corner marks and butting the tiles together, or laying
+// each tile over the Overlap of its neighbor, reassembles the code.
+// A caption in each page's bottom margin gives the tile's row and
+// column.
+type Tiles struct {
+	PageWidth, PageHeight float64 // page size; zero means A4
+	Margin                float64 // unprinted space at each page edge; zero means 15
+	Size                  float64 // side of the whole code, including the quiet zone
+	Border                int     // quiet zone around the code, in modules
+	Overlap               float64 // width of the code repeated at each shared tile edge
+}
+
+// markLen is the length of the registration marks.
+const markLen = 5.0
+
+// pages lays out the tiles of c.
+func (t *Tiles) pages(c *qr.Code) ([]*page, error) {
+	o := *t
+	if o.PageWidth == 0 && o.PageHeight == 0 {
+		o.PageWidth, o.PageHeight = A4Width, A4Height
+	}
+	if o.Margin == 0 {
+		o.Margin = 15
+	}
+	if c == nil {
+		return nil, fmt.Errorf("layout: no code")
+	}
+	// Tiles are at most aw × ah, sharing Overlap with each neighbor.
+	aw, ah := o.PageWidth-2*o.Margin, o.PageHeight-2*o.Margin
+	switch {
+	case o.Size <= 0 || o.Border < 0:
+		return nil, fmt.Errorf("layout: invalid size or border")
+	case o.Margin < markLen+1 || aw <= 0 || ah <= 0:
+		return nil, fmt.Errorf("layout: margins too large or too small for registration marks")
+	case o.Overlap < 0 || o.Overlap >= aw/2 || o.Overlap >= ah/2:
+		return nil, fmt.Errorf("layout: invalid overlap")
+	}
+	cols := tiles(o.Size, aw, o.Overlap)
+	rows := tiles(o.Size, ah, o.Overlap)
+
+	var pages []*page
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			// The tile is the part of the code in win,
+			// drawn at the page's margins.
+			win := rect{float64(col) * (aw - o.Overlap), float64(row) * (ah - o.Overlap), aw, ah}
+			win, _ = win.intersect(rect{0, 0, o.Size, o.Size})
+			p := &page{w: o.PageWidth, h: o.PageHeight}
+			x0, y0 := o.Margin-win.x, o.Margin-win.y
+			p.code(c, o.Border, x0, y0, o.Size, rect{o.Margin, o.Margin, win.w, win.h})
+			marks(p, rect{o.Margin, o.Margin, win.w, win.h})
+			p.text(o.PageWidth/2, o.PageHeight-o.Margin/3,
+				fmt.Sprintf("row %d of %d, column %d of %d", row+1, rows, col+1, cols), 8)
+			pages = append(pages, p)
+		}
+	}
+	return pages, nil
+}
+
+// tiles returns the number of tiles of width w, overlapping
+// their neighbors by overlap, needed to cover size.
+func tiles(size, w, overlap float64) int {
+	if size <= w {
+		return 1
+	}
+	return 1 + int(math.Ceil((size-w)/(w-overlap)-1e-9))
+}
+
+// marks draws registration marks around r: corner marks
+// extending the edges of r outward, and cross marks
+// centered on the middle of each edge, outside r.
+func marks(p *page, r rect) {
+	const gap = 1 // space between r and its corner marks
+	x0, y0, x1, y1 := r.x, r.y, r.x+r.w, r.y+r.h
+	for _, c := range [][2]float64{{x0, y0}, {x1, y0}, {x0, y1}, {x1, y1}} {
+		dx, dy := -1.0, -1.0
+		if c[0] == x1 {
+			dx = 1
+		}
+		if c[1] == y1 {
+			dy = 1
+		}
+		p.line(c[0], c[1]+dy*gap, c[0], c[1]+dy*(gap+markLen))
+		p.line(c[0]+dx*gap, c[1], c[0]+dx*(gap+markLen), c[1])
+	}
+	cross := func(x, y float64) {
+		p.line(x-markLen/2, y, x+markLen/2, y)
+		p.line(x, y-markLen/2, x, y+markLen/2)
+	}
+	const d = gap + markLen/2
+	cross((x0+x1)/2, y0-d)
+	cross((x0+x1)/2, y1+d)
+	cross(x0-d, (y0+y1)/2)
+	cross(x1+d, (y0+y1)/2)
+}
+
+// PDF returns a PDF document with one page for each tile of c.
+func (t *Tiles) PDF(c *qr.Code) ([]byte, error) {
+	pages, err := t.pages(c)
+	if err != nil {
+		return nil, err
+	}
+	return writePDF(pages), nil
+}
+
+// SVG returns an SVG document for each tile of c.
+func (t *Tiles) SVG(c *qr.Code) ([][]byte, error) {
+	pages, err := t.pages(c)
+	if err != nil {
+		return nil, err
+	}
+	var docs [][]byte
+	for _, p := range pages {
+		docs = append(docs, writeSVG(p))
+	}
+	return docs, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qr
+
+import "rsc.io/qr/coding"
+
+// penalty returns the penalty score of the code c,
+// as defined in ISO/IEC 18004 section 7.8.3.
+// Lower scores are better.
+func penalty(c *coding.Code) int {
+	n := c.Size
+	black := func(x, y int) bool { return c.Black(x, y) }
+	col := func(x, y int) bool { return c.Black(y, x) }
+
+	score := 0
+	dark := 0
+	for _, at := range []func(x, y int) bool{black, col} {
+		for y := 0; y < n; y++ {
+			// Rule 1: runs of five or more modules of the same color.
+			run := 1
+			for x := 1; x <= n; x++ {
+				if x < n && at(x, y) == at(x-1, y) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += 3 + run - 5
+				}
+				run = 1
+			}
+
+			// Rule 3: 1:1:3:1:1 finder-like patterns
+			// with four light modules on either side.
+			// Modules beyond the edge are light, like the quiet zone.
+			var bits uint
+			for x := -4; x < n+4; x++ {
+				bits <<= 1
+				if at(x, y) {
+					bits |= 1
+				}
+				if x >= 6 && (bits&0x7ff == 0x5d0 || bits&0x7ff == 0x05d) {
+					score += 40
+				}
+			}
+		}
+	}
+
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			b := c.Black(x, y)
+			if b {
+				dark++
+			}
+			// Rule 2: 2×2 blocks of the same color.
+			if x+1 < n && y+1 < n && b == c.Black(x+1, y) && b == c.Black(x, y+1) && b == c.Black(x+1, y+1) {
+				score += 3
+			}
+		}
+	}
+
+	// Rule 4: imbalance of dark and light modules,
+	// 10 points for each 5% away from half.
+	pct := dark * 100 / (n * n)
+	if pct < 50 {
+		pct = 100 - pct
+	}
+	score += (pct - 50) / 5 * 10
+	return score
+}
@@ -0,0 +1,51 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qr
+
+import (
+	"testing"
+
+	"rsc.io/qr/coding"
+)
+
+func TestPenalty(t *testing.T) {
+	// newCode returns a 21×21 code with black pixels where f is true.
+	newCode := func(f func(x, y int) bool) *coding.Code {
+		c := &coding.Code{Size: 21, Stride: 3, Bitmap: make([]byte, 3*21)}
+		for y := 0; y < 21; y++ {
+			for x := 0; x < 21; x++ {
+				if f(x, y) {
+					c.Bitmap[y*3+x/8] |= 0x80 >> uint(x%8)
+				}
+			}
+		}
+		return c
+	}
+	for _, tt := range []struct {
+		name string
+		f    func(x, y int) bool
+		want int
+	}{
+		// Rule 1: 42 runs of 21 score 3+16 each.
+		// Rule 2: 20×20 blocks score 3 each.
+		// Rule 4: 0% dark scores 100.
+		{"white", func(x, y int) bool { return false }, 42*19 + 400*3 + 100},
+		// Rule 4: 221 of 441 dark is 50%, scoring 0. No runs or blocks.
+		{"checker", func(x, y int) bool { return (x+y)%2 == 1 }, 0},
+		// A 1:1:3:1:1 pattern in row 10, columns 7 to 13.
+		// Rule 1: 20 rows score 19, and row 10 has two runs of 7 scoring 5.
+		// 16 columns score 19, and 5 columns have two runs of 10 scoring 8.
+		// Rule 2: 16 blocks in rows 9 to 11 include black pixels.
+		// Rule 3: the pattern has four light modules on both sides.
+		// Rule 4: 1% dark scores 90.
+		{"finder", func(x, y int) bool { return y == 10 && (x == 7 || 9 <= x && x <= 11 || x == 13) },
+			20*19 + 2*5 + 16*19 + 5*2*8 + (400-16)*3 + 2*40 + 90},
+	} {
+		if got := penalty(newCode(tt.f)); got != tt.want {
+			t.Errorf("%s: penalty = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// normalizeIBAN returns s with spaces removed and letters in upper case,
+// or an error if it is not a valid IBAN.
+func normalizeIBAN(s string) (string, error) {
+	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	if len(s) < 15 || len(s) > 34 {
+		return "", errors.New("IBAN must have 15 to 34 characters")
+	}
+	if !isUpper(s[:2]) || !isDigits(s[2:4]) || !isAlnum(s[4:]) {
+		return "", errors.New("malformed IBAN")
+	}
+	if mod97(s[4:]+s[:4]) != 1 {
+		return "", errors.New("IBAN check digits do not match")
+	}
+	return s, nil
+}
+
+// checkBIC reports whether s is a valid BIC:
+// a 4-letter bank code, a 2-letter country code,
+// a 2-character location code, and an optional
+// 3-character branch code.
+func checkBIC(s string) error {
+	if (len(s) != 8 && len(s) != 11) || !isUpper(s[:6]) || !isAlnum(s[6:]) {
+		return errors.New("malformed BIC")
+	}
+	return nil
+}
+
+// checkRF reports whether s is a valid ISO 11649 creditor reference:
+// "RF", two check digits, and up to 21 letters or digits.
+func checkRF(s string) error {
+	if len(s) < 5 || len(s) > 25 || s[:2] != "RF" || !isDigits(s[2:4]) || !isAlnum(s[4:]) {
+		return errors.New("malformed creditor reference")
+	}
+	if mod97(s[4:]+s[:4]) != 1 {
+		return errors.New("creditor reference check digits do not match")
+	}
+	return nil
+}
+
+// checkAmount reports whether s is a valid amount:
+// from 0.01 to 999999999.99, with at most two decimals.
+func checkAmount(s string) error {
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+		if frac == "" || len(frac) > 2 || !isDigits(frac) {
+			return fmt.Errorf("amount %q must have one or two decimals", s)
+		}
+	}
+	if whole == "" || len(whole) > 9 || !isDigits(whole) {
+		return fmt.Errorf("malformed amount %q", s)
+	}
+	if strings.Trim(whole+frac, "0") == "" {
+		return errors.New("amount must be at least 0.01")
+	}
+	return nil
+}
+
+// mod97 returns the ISO 7064 MOD 97-10 remainder of s,
+// which must consist of digits and upper-case letters.
+// Each letter stands for two digits: A is 10, B is 11, and so on.
+func mod97(s string) int {
+	r := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' {
+			r = (r*100 + int(c-'A'+10)) % 97
+		} else {
+			r = (r*10 + int(c-'0')) % 97
+		}
+	}
+	return r
+}
+
+func isDigits(s string) bool {
+	return strings.Trim(s, "0123456789") == ""
+}
+
+func isUpper(s string) bool {
+	return strings.Trim(s, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == ""
+}
+
+func isAlnum(s string) bool {
+	return strings.Trim(s, "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ") == ""
+}
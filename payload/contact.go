@@ -0,0 +1,449 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"rsc.io/qr"
+)
+
+// A Contact describes a person or organization,
+// as carried by vCard and MECARD codes.
+type Contact struct {
+	// Name is the name to display.
+	// The empty string means GivenName followed by FamilyName,
+	// or Org if those are empty too.
+	// MECARD has no separate display name: it uses Name only
+	// when FamilyName and GivenName are both empty.
+	Name       string
+	FamilyName string
+	GivenName  string
+
+	Org     string
+	Phones  []string
+	Emails  []string
+	URL     string
+	Address Address
+	Note    string
+}
+
+// An Address is a postal address.
+// Its fields follow the order of the vCard ADR property.
+type Address struct {
+	POBox      string
+	Extended   string // apartment or suite number
+	Street     string
+	City       string
+	Region     string // state or province
+	PostalCode string
+	Country    string
+}
+
+func (a *Address) fields() []string {
+	return []string{a.POBox, a.Extended, a.Street, a.City, a.Region, a.PostalCode, a.Country}
+}
+
+func (a *Address) setFields(f []string) {
+	for len(f) < 7 {
+		f = append(f, "")
+	}
+	*a = Address{f[0], f[1], f[2], f[3], f[4], f[5], f[6]}
+}
+
+// A ContactFormat is a text format for a Contact.
+type ContactFormat int
+
+const (
+	MECARD ContactFormat = iota // NTT Docomo MECARD
+	VCard3                      // vCard 3.0 (RFC 2426)
+	VCard4                      // vCard 4.0 (RFC 6350)
+)
+
+func (f ContactFormat) String() string {
+	switch f {
+	case MECARD:
+		return "MECARD"
+	case VCard3:
+		return "vCard 3.0"
+	case VCard4:
+		return "vCard 4.0"
+	}
+	return fmt.Sprintf("ContactFormat(%d)", int(f))
+}
+
+// displayName returns the name to use for the vCard FN property.
+func (c *Contact) displayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	if n := strings.TrimSpace(c.GivenName + " " + c.FamilyName); n != "" {
+		return n
+	}
+	return c.Org
+}
+
+// Check reports whether c describes a valid contact.
+func (c *Contact) Check() error {
+	if c.displayName() == "" {
+		return errors.New("contact: missing name")
+	}
+	for _, p := range c.Phones {
+		if p == "" {
+			return errors.New("contact: empty phone number")
+		}
+	}
+	for _, e := range c.Emails {
+		if e == "" {
+			return errors.New("contact: empty email address")
+		}
+	}
+	fields := []string{c.Name, c.FamilyName, c.GivenName, c.Org, c.URL, c.Note}
+	fields = append(fields, c.Phones...)
+	fields = append(fields, c.Emails...)
+	fields = append(fields, c.Address.fields()...)
+	for _, f := range fields {
+		if !utf8.ValidString(f) {
+			return fmt.Errorf("contact: invalid UTF-8 in %q", f)
+		}
+	}
+	return nil
+}
+
+// String returns the vCard 3.0 text for c,
+// the format most widely understood by readers.
+func (c *Contact) String() string {
+	return c.Text(VCard3)
+}
+
+// Text returns the text for c in format f.
+// It does not check that c is valid; see Check.
+func (c *Contact) Text(f ContactFormat) string {
+	if f == MECARD {
+		return c.mecard()
+	}
+	return c.vcard(f)
+}
+
+// Encode returns c encoded as a QR code at level l,
+// using whichever format, MECARD, vCard 3.0, or vCard 4.0,
+// gives the smallest code.
+func (c *Contact) Encode(l qr.Level) (*qr.Code, error) {
+	code, _, err := c.encode(l)
+	return code, err
+}
+
+// Format returns the format that Encode uses for c at level l.
+func (c *Contact) Format(l qr.Level) (ContactFormat, error) {
+	_, f, err := c.encode(l)
+	return f, err
+}
+
+func (c *Contact) encode(l qr.Level) (*qr.Code, ContactFormat, error) {
+	if err := c.Check(); err != nil {
+		return nil, 0, err
+	}
+	var (
+		best     *qr.Code
+		bestInfo *qr.Info
+		bestFmt  ContactFormat
+		lastErr  error
+	)
+	for _, f := range []ContactFormat{MECARD, VCard3, VCard4} {
+		code, info, err := qr.EncodeOptions(c.Text(f), l, nil)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if best == nil || info.Version < bestInfo.Version || info.Version == bestInfo.Version && info.Bits < bestInfo.Bits {
+			best, bestInfo, bestFmt = code, info, f
+		}
+	}
+	if best == nil {
+		return nil, 0, lastErr
+	}
+	return best, bestFmt, nil
+}
+
+// mecardSpecial lists the bytes that must be escaped in MECARD fields.
+const mecardSpecial = `\;,:`
+
+func (c *Contact) mecard() string {
+	var b strings.Builder
+	field := func(key, val string) {
+		if val != "" {
+			b.WriteString(key + ":" + val + ";")
+		}
+	}
+	b.WriteString("MECARD:")
+	if c.FamilyName != "" || c.GivenName != "" {
+		field("N", escape(c.FamilyName, mecardSpecial)+","+escape(c.GivenName, mecardSpecial))
+	} else {
+		field("N", escape(c.Name, mecardSpecial))
+	}
+	field("ORG", escape(c.Org, mecardSpecial))
+	for _, p := range c.Phones {
+		field("TEL", escape(p, mecardSpecial))
+	}
+	for _, e := range c.Emails {
+		field("EMAIL", escape(e, mecardSpecial))
+	}
+	field("URL", escape(c.URL, mecardSpecial))
+	if c.Address != (Address{}) {
+		var f []string
+		for _, s := range c.Address.fields() {
+			f = append(f, escape(s, mecardSpecial))
+		}
+		field("ADR", strings.Join(f, ","))
+	}
+	field("NOTE", escape(c.Note, mecardSpecial))
+	b.WriteString(";")
+	return b.String()
+}
+
+// vcardSpecial lists the bytes that must be escaped in vCard text values.
+// Newlines are escaped too, as \n.
+const vcardSpecial = `\;,`
+
+// vcardEscape escapes s for use as a vCard text value.
+func vcardEscape(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(escape(s, vcardSpecial), "\n", `\n`)
+}
+
+// vcardUnescape undoes vcardEscape.
+func vcardUnescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == 'n' || s[i] == 'N' {
+				b.WriteByte('\n')
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func (c *Contact) vcard(f ContactFormat) string {
+	var b strings.Builder
+	line := func(key, val string) {
+		if val != "" {
+			fold(&b, key+":"+val)
+		}
+	}
+	line("BEGIN", "VCARD")
+	if f == VCard4 {
+		line("VERSION", "4.0")
+	} else {
+		line("VERSION", "3.0")
+	}
+	// N is required in vCard 3.0 but optional in 4.0.
+	if f != VCard4 || c.FamilyName != "" || c.GivenName != "" {
+		fold(&b, "N:"+vcardEscape(c.FamilyName)+";"+vcardEscape(c.GivenName)+";;;")
+	}
+	line("FN", vcardEscape(c.displayName()))
+	line("ORG", vcardEscape(c.Org))
+	for _, p := range c.Phones {
+		line("TEL", vcardEscape(p))
+	}
+	for _, e := range c.Emails {
+		line("EMAIL", vcardEscape(e))
+	}
+	line("URL", vcardEscape(c.URL))
+	if c.Address != (Address{}) {
+		var f []string
+		for _, s := range c.Address.fields() {
+			f = append(f, vcardEscape(s))
+		}
+		line("ADR", strings.Join(f, ";"))
+	}
+	line("NOTE", vcardEscape(c.Note))
+	line("END", "VCARD")
+	return b.String()
+}
+
+// maxLine is the longest vCard line, in bytes, not counting the CRLF.
+const maxLine = 75
+
+// fold writes the vCard content line s to b, ending it with CRLF
+// and folding it onto continuation lines, each starting with a space,
+// to keep every line at most maxLine bytes.
+// It does not split valid UTF-8 sequences.
+func fold(b *strings.Builder, s string) {
+	n := maxLine
+	for len(s) > n {
+		i := n
+		for i > 0 && !utf8.RuneStart(s[i]) {
+			i--
+		}
+		if i == 0 {
+			// Not UTF-8: split anywhere.
+			i = n
+		}
+		b.WriteString(s[:i] + "\r\n ")
+		s = s[i:]
+		n = maxLine - 1
+	}
+	b.WriteString(s + "\r\n")
+}
+
+// ParseContact parses vCard or MECARD text,
+// as returned by Text, into a Contact.
+func ParseContact(text string) (*Contact, error) {
+	var c *Contact
+	var err error
+	switch {
+	case strings.HasPrefix(text, "MECARD:"):
+		c, err = parseMECARD(text[len("MECARD:"):])
+	case len(text) >= len("BEGIN:VCARD") && strings.EqualFold(text[:len("BEGIN:VCARD")], "BEGIN:VCARD"):
+		c, err = parseVCard(text)
+	default:
+		return nil, errors.New("contact: not a vCard or MECARD")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Check(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func parseMECARD(text string) (*Contact, error) {
+	c := new(Contact)
+	for _, f := range split(text, ';') {
+		if f == "" {
+			continue
+		}
+		key, val, ok := cut(f, ':')
+		if !ok {
+			return nil, fmt.Errorf("contact: malformed MECARD field %q", f)
+		}
+		switch strings.ToUpper(key) {
+		case "N":
+			if family, given, ok := cut(val, ','); ok {
+				c.FamilyName, c.GivenName = unescape(family), unescape(given)
+			} else {
+				c.Name = unescape(val)
+			}
+		case "ORG":
+			c.Org = unescape(val)
+		case "TEL":
+			c.Phones = append(c.Phones, unescape(val))
+		case "EMAIL":
+			c.Emails = append(c.Emails, unescape(val))
+		case "URL":
+			c.URL = unescape(val)
+		case "ADR":
+			parts := split(val, ',')
+			if len(parts) != 7 {
+				// Not the structured form: keep it as one line.
+				c.Address = Address{Street: unescape(val)}
+				break
+			}
+			for i := range parts {
+				parts[i] = unescape(parts[i])
+			}
+			c.Address.setFields(parts)
+		case "NOTE":
+			c.Note = unescape(val)
+		}
+	}
+	return c, nil
+}
+
+func parseVCard(text string) (*Contact, error) {
+	// Unfold continuation lines.
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\n ", "")
+	text = strings.ReplaceAll(text, "\n\t", "")
+
+	c := new(Contact)
+	fn := ""
+	end := false
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" {
+			continue
+		}
+		name, val, ok := cutValue(line)
+		if !ok {
+			return nil, fmt.Errorf("contact: malformed vCard line %q", line)
+		}
+		// Drop the parameters and any group prefix.
+		if i := strings.IndexByte(name, ';'); i >= 0 {
+			name = name[:i]
+		}
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			name = name[i+1:]
+		}
+		switch strings.ToUpper(name) {
+		case "END":
+			end = true
+		case "N":
+			parts := split(val, ';')
+			c.FamilyName = vcardUnescape(parts[0])
+			if len(parts) > 1 {
+				c.GivenName = vcardUnescape(parts[1])
+			}
+		case "FN":
+			fn = vcardUnescape(val)
+		case "ORG":
+			// Keep only the organization name, not its units.
+			org, _, _ := cut(val, ';')
+			c.Org = vcardUnescape(org)
+		case "TEL":
+			c.Phones = append(c.Phones, strings.TrimPrefix(vcardUnescape(val), "tel:"))
+		case "EMAIL":
+			c.Emails = append(c.Emails, vcardUnescape(val))
+		case "URL":
+			c.URL = vcardUnescape(val)
+		case "ADR":
+			if c.Address != (Address{}) {
+				break // keep only the first address
+			}
+			parts := split(val, ';')
+			for i := range parts {
+				parts[i] = vcardUnescape(parts[i])
+			}
+			c.Address.setFields(parts)
+		case "NOTE":
+			c.Note = vcardUnescape(val)
+		}
+	}
+	if !end {
+		return nil, errors.New("contact: missing END:VCARD")
+	}
+	if fn != c.displayName() {
+		c.Name = fn
+	}
+	return c, nil
+}
+
+// cutValue splits a vCard content line into its name,
+// with any parameters, and its value, at the first colon
+// not inside a quoted parameter value.
+func cutValue(line string) (name, value string, ok bool) {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				return line[:i], line[i+1:], true
+			}
+		}
+	}
+	return line, "", false
+}
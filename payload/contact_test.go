@@ -0,0 +1,228 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"bytes"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"rsc.io/qr"
+	"rsc.io/qr/decode"
+)
+
+var contacts = []Contact{
+	{GivenName: "Ada", FamilyName: "Lovelace"},
+	{Org: "Analytical Engines, Ltd."},
+	{Name: "Dr. Grace Hopper", GivenName: "Grace", FamilyName: "Hopper", Org: "US Navy"},
+	{
+		GivenName:  "Jean",
+		FamilyName: "Müller; Söhne",
+		Org:        `Back\slash, Inc.`,
+		Phones:     []string{"+41 44 555 01 23", "+41 79 555 99 88"},
+		Emails:     []string{"jean@example.ch", "j.m@example.org"},
+		URL:        "https://example.ch/~jean",
+		Address:    Address{Street: "Bahnhofstrasse 1", City: "Zürich", PostalCode: "8001", Country: "Switzerland"},
+		Note:       "Met at the conference.\nLikes coffee: black; no sugar, please. " + strings.Repeat("αβγ", 20),
+	},
+}
+
+func TestContactVCard(t *testing.T) {
+	want := "BEGIN:VCARD\r\n" +
+		"VERSION:3.0\r\n" +
+		"N:Lovelace\\;Byron;Ada;;;\r\n" +
+		"FN:Ada Lovelace\\;Byron\r\n" +
+		"TEL:+44 20 1234\r\n" +
+		"ADR:;;12 St\\, James's Sq;London;;;UK\r\n" +
+		"NOTE:line 1\\nline 2\r\n" +
+		"END:VCARD\r\n"
+	c := Contact{
+		GivenName:  "Ada",
+		FamilyName: "Lovelace;Byron",
+		Phones:     []string{"+44 20 1234"},
+		Address:    Address{Street: "12 St, James's Sq", City: "London", Country: "UK"},
+		Note:       "line 1\nline 2",
+	}
+	if s := c.String(); s != want {
+		t.Errorf("String() =\n%s\nwant\n%s", s, want)
+	}
+
+	// vCard 4.0 leaves out an empty N.
+	c = Contact{Org: "Example"}
+	want = "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Example\r\nORG:Example\r\nEND:VCARD\r\n"
+	if s := c.Text(VCard4); s != want {
+		t.Errorf("Text(VCard4) =\n%s\nwant\n%s", s, want)
+	}
+}
+
+func TestContactFold(t *testing.T) {
+	c := contacts[len(contacts)-1]
+	for _, f := range []ContactFormat{VCard3, VCard4} {
+		text := c.Text(f)
+		if !strings.HasSuffix(text, "\r\n") {
+			t.Errorf("%v: missing final CRLF", f)
+		}
+		folded := false
+		for _, line := range strings.Split(strings.TrimSuffix(text, "\r\n"), "\r\n") {
+			if len(line) > 75 {
+				t.Errorf("%v: line too long (%d bytes): %s", f, len(line), line)
+			}
+			if !utf8.ValidString(line) {
+				t.Errorf("%v: folded line splits UTF-8: %q", f, line)
+			}
+			if strings.HasPrefix(line, " ") {
+				folded = true
+			}
+		}
+		if !folded {
+			t.Errorf("%v: long NOTE not folded", f)
+		}
+	}
+}
+
+func TestContactInvalidUTF8(t *testing.T) {
+	c := Contact{Name: "x", Note: strings.Repeat("\x80", 100)}
+	if err := c.Check(); err == nil || !strings.Contains(err.Error(), "UTF-8") {
+		t.Errorf("Check() = %v, want invalid UTF-8 error", err)
+	}
+	if _, err := c.Encode(qr.M); err == nil {
+		t.Errorf("Encode succeeded, want error")
+	}
+	// Text does not check c, but it must still fold the long line.
+	for _, line := range strings.Split(c.Text(VCard3), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line too long (%d bytes): %q", len(line), line)
+		}
+	}
+}
+
+func TestContactMECARD(t *testing.T) {
+	c := Contact{
+		GivenName:  "Taro",
+		FamilyName: "Yamada",
+		Phones:     []string{"03-1234-5678"},
+		Emails:     []string{"taro@example.jp"},
+		URL:        "http://example.jp",
+		Note:       "a;b,c:d",
+	}
+	want := `MECARD:N:Yamada,Taro;TEL:03-1234-5678;EMAIL:taro@example.jp;URL:http\://example.jp;NOTE:a\;b\,c\:d;;`
+	if s := c.Text(MECARD); s != want {
+		t.Errorf("Text(MECARD) = %s, want %s", s, want)
+	}
+}
+
+func TestContactRoundTrip(t *testing.T) {
+	for _, c := range contacts {
+		for _, f := range []ContactFormat{MECARD, VCard3, VCard4} {
+			text := c.Text(f)
+			got, err := ParseContact(text)
+			if err != nil {
+				t.Errorf("%v: ParseContact(%q): %v", f, text, err)
+				continue
+			}
+			want := c
+			if f == MECARD && (c.GivenName != "" || c.FamilyName != "") {
+				want.Name = "" // MECARD has no display name
+			}
+			if !reflect.DeepEqual(*got, want) {
+				t.Errorf("%v: ParseContact(%q) =\n%+v\nwant\n%+v", f, text, *got, want)
+			}
+		}
+	}
+}
+
+func TestParseContact(t *testing.T) {
+	// Parameters, groups, LF line endings, tab folding, and tel: URIs.
+	text := "begin:vcard\nVERSION:4.0\nitem1.TEL;TYPE=\"cell,voice\":tel:+1-555-0100\n" +
+		"FN;LANGUAGE=en:Sam\n\tple\nORG:Acme;Research\nADR;TYPE=work:;;1 Main St;Town\nEND:VCARD\n"
+	c, err := ParseContact(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Contact{
+		Name:    "Sample",
+		Org:     "Acme",
+		Phones:  []string{"+1-555-0100"},
+		Address: Address{Street: "1 Main St", City: "Town"},
+	}
+	if !reflect.DeepEqual(*c, want) {
+		t.Errorf("ParseContact =\n%+v\nwant\n%+v", *c, want)
+	}
+
+	// A free-form MECARD address.
+	c, err = ParseContact(`MECARD:N:Kim;ADR:1 Main St, Town;;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "Kim" || c.Address != (Address{Street: "1 Main St, Town"}) {
+		t.Errorf("ParseContact(MECARD) = %+v", *c)
+	}
+
+	for _, text := range []string{
+		"",
+		"hello",
+		"BEGIN:VCARD\r\nFN:x\r\n",
+		"BEGIN:VCARD\r\nFN x\r\nEND:VCARD\r\n",
+		"BEGIN:VCARD\r\nNOTE:no name\r\nEND:VCARD\r\n",
+		"MECARD:N;;",
+		"MECARD:TEL:123;;",
+	} {
+		if c, err := ParseContact(text); err == nil {
+			t.Errorf("ParseContact(%q) = %+v, want error", text, *c)
+		}
+	}
+}
+
+func TestContactEncode(t *testing.T) {
+	for _, c := range contacts {
+		code, err := c.Encode(qr.M)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := c.Format(qr.M)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The chosen format gives the smallest code.
+		_, info, err := qr.EncodeOptions(c.Text(f), qr.M, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, g := range []ContactFormat{MECARD, VCard3, VCard4} {
+			_, gi, err := qr.EncodeOptions(c.Text(g), qr.M, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gi.Version < info.Version {
+				t.Errorf("%+v: chose %v (version %d), but %v is version %d", c, f, info.Version, g, gi.Version)
+			}
+		}
+
+		m, err := png.Decode(bytes.NewReader(code.PNG()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := decode.Decode(m)
+		if err != nil {
+			t.Fatalf("%+v: decode: %v", c, err)
+		}
+		if r.Text != c.Text(f) {
+			t.Errorf("%+v: decoded %q, want %q", c, r.Text, c.Text(f))
+		}
+	}
+
+	var c Contact
+	if _, err := c.Encode(qr.M); err == nil {
+		t.Errorf("empty contact: Encode succeeded, want error")
+	}
+	c = Contact{Name: "x", Phones: []string{""}}
+	if _, err := c.Encode(qr.M); err == nil {
+		t.Errorf("empty phone: Encode succeeded, want error")
+	}
+}
@@ -0,0 +1,354 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"rsc.io/qr"
+)
+
+// An EMV is an EMVCo merchant-presented mode (MPM) payment payload,
+// the format behind most point-of-sale payment codes.
+//
+// The text is a sequence of fields, each a two-digit ID,
+// a two-digit length, and a value. Template fields hold
+// nested fields in their value. The last field, ID 63,
+// is a CRC-16/CCITT checksum of all the text before it.
+type EMV struct {
+	// Fields lists the fields, other than the CRC.
+	// String writes them in ID order, followed by the CRC.
+	Fields []EMVField
+}
+
+// An EMVField is a field in an EMV payload.
+// A template field has nested Fields instead of a Value.
+type EMVField struct {
+	ID     int
+	Value  string
+	Fields []EMVField
+}
+
+// Top-level EMV field IDs.
+const (
+	EMVFormat     = 0  // payload format indicator, always "01"
+	EMVInitiation = 1  // "11" for a static code, "12" for a dynamic one
+	EMVCategory   = 52 // merchant category code
+	EMVCurrency   = 53 // ISO 4217 numeric currency code, such as "986"
+	EMVAmount     = 54 // transaction amount
+	EMVTip        = 55 // tip or convenience fee indicator
+	EMVFixedFee   = 56 // convenience fee, fixed
+	EMVPercentFee = 57 // convenience fee, percentage
+	EMVCountry    = 58 // ISO 3166-1 two-letter country code
+	EMVName       = 59 // merchant name
+	EMVCity       = 60 // merchant city
+	EMVPostalCode = 61 // merchant postal code
+	EMVAdditional = 62 // additional data field template
+	EMVCRC        = 63 // CRC, added by String
+	EMVLanguage   = 64 // merchant information in an alternate language
+)
+
+const (
+	maxEMV         = 512 // longest payload, in characters
+	maxEMVValue    = 99  // longest field value, in characters
+	emvCRCField    = "6304"
+	emvCRCFieldLen = len(emvCRCField) + 4
+)
+
+// isEMVTemplate reports whether the top-level field id holds nested fields.
+func isEMVTemplate(id int) bool {
+	return 26 <= id && id <= 51 || id == EMVAdditional || id == EMVLanguage || 80 <= id && id <= 99
+}
+
+// Field returns the top-level field with the given ID, or nil.
+func (e *EMV) Field(id int) *EMVField {
+	for i := range e.Fields {
+		if e.Fields[i].ID == id {
+			return &e.Fields[i]
+		}
+	}
+	return nil
+}
+
+// Field returns the nested field with the given ID, or nil.
+func (f *EMVField) Field(id int) *EMVField {
+	for i := range f.Fields {
+		if f.Fields[i].ID == id {
+			return &f.Fields[i]
+		}
+	}
+	return nil
+}
+
+// text returns the value of f as written in the payload.
+func (f *EMVField) text() string {
+	if f.Fields == nil {
+		return f.Value
+	}
+	return writeEMV(f.Fields)
+}
+
+// writeEMV returns the fields in ID order as ID, length, value triples.
+func writeEMV(fields []EMVField) string {
+	fields = append([]EMVField(nil), fields...)
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].ID < fields[j].ID })
+	var b strings.Builder
+	for _, f := range fields {
+		v := f.text()
+		fmt.Fprintf(&b, "%02d%02d%s", f.ID, utf8.RuneCountInString(v), v)
+	}
+	return b.String()
+}
+
+// String returns the EMV text for e, ending with the CRC field.
+// It does not check that e is valid; see Check.
+func (e *EMV) String() string {
+	s := writeEMV(e.Fields) + emvCRCField
+	return s + fmt.Sprintf("%04X", crc16(s))
+}
+
+// crc16 returns the CRC-16/CCITT-FALSE checksum of s:
+// polynomial 0x1021, initial value 0xFFFF.
+func crc16(s string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// Check reports whether e is a valid payload:
+// it must have the required fields, and each field must have
+// the length and format that the EMVCo specification gives it.
+func (e *EMV) Check() error {
+	seen := make(map[int]bool)
+	accounts := 0
+	for i := range e.Fields {
+		f := &e.Fields[i]
+		if seen[f.ID] {
+			return fmt.Errorf("emv: duplicate field %02d", f.ID)
+		}
+		seen[f.ID] = true
+		if err := checkEMVField(f); err != nil {
+			return err
+		}
+		if 2 <= f.ID && f.ID <= 51 {
+			accounts++
+		}
+	}
+	for _, id := range []int{EMVFormat, EMVCategory, EMVCurrency, EMVCountry, EMVName, EMVCity} {
+		if !seen[id] {
+			return fmt.Errorf("emv: missing required field %02d", id)
+		}
+	}
+	if accounts == 0 {
+		return errors.New("emv: missing merchant account information")
+	}
+	tip := ""
+	if f := e.Field(EMVTip); f != nil {
+		tip = f.Value
+	}
+	if seen[EMVFixedFee] != (tip == "02") {
+		return fmt.Errorf("emv: field %02d goes with tip indicator 02", EMVFixedFee)
+	}
+	if seen[EMVPercentFee] != (tip == "03") {
+		return fmt.Errorf("emv: field %02d goes with tip indicator 03", EMVPercentFee)
+	}
+	if n := utf8.RuneCountInString(e.String()); n > maxEMV {
+		return fmt.Errorf("emv: payload has %d characters, more than %d", n, maxEMV)
+	}
+	return nil
+}
+
+// checkEMVField checks the top-level field f.
+func checkEMVField(f *EMVField) error {
+	if f.ID < 0 || f.ID > 99 || f.ID == EMVCRC {
+		return fmt.Errorf("emv: invalid field ID %d", f.ID)
+	}
+	if isEMVTemplate(f.ID) {
+		if f.Fields == nil {
+			return fmt.Errorf("emv: template field %02d has no nested fields", f.ID)
+		}
+		return checkEMVTemplate(f)
+	}
+	if f.Fields != nil {
+		return fmt.Errorf("emv: field %02d is not a template", f.ID)
+	}
+	if err := checkEMVValue(f.ID, f.Value, f.ID != EMVLanguage); err != nil {
+		return err
+	}
+
+	v := f.Value
+	var bad bool
+	switch f.ID {
+	case EMVFormat:
+		bad = v != "01"
+	case EMVInitiation:
+		bad = v != "11" && v != "12"
+	case EMVCategory:
+		bad = len(v) != 4 || !isDigits(v)
+	case EMVCurrency:
+		bad = len(v) != 3 || !isDigits(v)
+	case EMVAmount, EMVFixedFee:
+		bad = len(v) > 13 || !validAmount(v)
+	case EMVTip:
+		bad = v != "01" && v != "02" && v != "03"
+	case EMVPercentFee:
+		bad = len(v) > 5 || !validAmount(v)
+	case EMVCountry:
+		bad = len(v) != 2 || !isUpper(v)
+	case EMVName:
+		bad = v == "" || utf8.RuneCountInString(v) > 25
+	case EMVCity:
+		bad = v == "" || utf8.RuneCountInString(v) > 15
+	case EMVPostalCode:
+		bad = utf8.RuneCountInString(v) > 10
+	}
+	if bad {
+		return fmt.Errorf("emv: invalid value %q for field %02d", v, f.ID)
+	}
+	return nil
+}
+
+// validAmount reports whether v is a positive decimal number,
+// with digits and at most one decimal point.
+func validAmount(v string) bool {
+	if strings.Count(v, ".") > 1 || !isDigits(strings.Replace(v, ".", "", 1)) {
+		return false
+	}
+	return strings.Trim(v, "0.") != ""
+}
+
+// checkEMVTemplate checks the nested fields of the template f.
+func checkEMVTemplate(f *EMVField) error {
+	seen := make(map[int]bool)
+	for _, sub := range f.Fields {
+		if sub.ID < 0 || sub.ID > 99 || seen[sub.ID] {
+			return fmt.Errorf("emv: invalid or duplicate field %02d in template %02d", sub.ID, f.ID)
+		}
+		seen[sub.ID] = true
+		if sub.Fields != nil {
+			return fmt.Errorf("emv: field %02d in template %02d is not a template", sub.ID, f.ID)
+		}
+		if err := checkEMVValue(sub.ID, sub.Value, f.ID != EMVLanguage); err != nil {
+			return fmt.Errorf("%v in template %02d", err, f.ID)
+		}
+		if f.ID == EMVAdditional && 1 <= sub.ID && sub.ID <= 9 && utf8.RuneCountInString(sub.Value) > 25 {
+			return fmt.Errorf("emv: field %02d in template %02d longer than 25 characters", sub.ID, f.ID)
+		}
+	}
+	switch {
+	case f.ID == EMVLanguage:
+		if !seen[0] || !seen[1] {
+			return fmt.Errorf("emv: template %02d needs a language and a name", f.ID)
+		}
+	case f.ID != EMVAdditional:
+		// Merchant account and unreserved templates
+		// start with a globally unique identifier.
+		if !seen[0] {
+			return fmt.Errorf("emv: template %02d missing globally unique identifier", f.ID)
+		}
+	}
+	if n := utf8.RuneCountInString(f.text()); n > maxEMVValue {
+		return fmt.Errorf("emv: template %02d has %d characters, more than %d", f.ID, n, maxEMVValue)
+	}
+	return nil
+}
+
+// checkEMVValue checks that the value v of field id fits in a field
+// and, if ascii is set, uses only printable ASCII characters.
+func checkEMVValue(id int, v string, ascii bool) error {
+	if n := utf8.RuneCountInString(v); n > maxEMVValue {
+		return fmt.Errorf("emv: field %02d has %d characters, more than %d", id, n, maxEMVValue)
+	}
+	for _, r := range v {
+		if r < 0x20 || r == 0x7F || ascii && r > 0x7F {
+			return fmt.Errorf("emv: field %02d contains invalid character %q", id, r)
+		}
+	}
+	return nil
+}
+
+// Encode returns e encoded as a QR code at level l.
+func (e *EMV) Encode(l qr.Level) (*qr.Code, error) {
+	if err := e.Check(); err != nil {
+		return nil, err
+	}
+	return qr.Encode(e.String(), l)
+}
+
+// ParseEMV parses EMV text, as returned by String,
+// checking its CRC and its fields.
+func ParseEMV(text string) (*EMV, error) {
+	if len(text) < emvCRCFieldLen || text[len(text)-emvCRCFieldLen:len(text)-4] != emvCRCField {
+		return nil, errors.New("emv: missing CRC field")
+	}
+	body := text[:len(text)-4]
+	if want := fmt.Sprintf("%04X", crc16(body)); !strings.EqualFold(text[len(body):], want) {
+		return nil, fmt.Errorf("emv: CRC %s does not match computed %s", text[len(body):], want)
+	}
+	fields, err := parseEMV(body[:len(body)-len(emvCRCField)], true)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 || fields[0].ID != EMVFormat {
+		return nil, errors.New("emv: payload does not start with format indicator")
+	}
+	e := &EMV{Fields: fields}
+	if err := e.Check(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// parseEMV parses the ID, length, value triples in s.
+// If top is set, it parses the values of template fields
+// as nested fields.
+func parseEMV(s string, top bool) ([]EMVField, error) {
+	var fields []EMVField
+	for s != "" {
+		if len(s) < 4 || !isDigits(s[:4]) {
+			return nil, fmt.Errorf("emv: malformed field at %q", s)
+		}
+		id := int(s[0]-'0')*10 + int(s[1]-'0')
+		n := int(s[2]-'0')*10 + int(s[3]-'0')
+		s = s[4:]
+
+		// The length counts characters, not bytes.
+		i := 0
+		for j := 0; j < n; j++ {
+			if i >= len(s) {
+				return nil, fmt.Errorf("emv: field %02d truncated", id)
+			}
+			_, size := utf8.DecodeRuneInString(s[i:])
+			i += size
+		}
+		f := EMVField{ID: id, Value: s[:i]}
+		s = s[i:]
+		if top && isEMVTemplate(id) {
+			sub, err := parseEMV(f.Value, false)
+			if err != nil {
+				return nil, err
+			}
+			f.Value, f.Fields = "", sub
+			if f.Fields == nil {
+				f.Fields = []EMVField{}
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
@@ -0,0 +1,276 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+
+	"rsc.io/qr"
+	"rsc.io/qr/decode"
+)
+
+// pixExample is the example from the Banco Central do Brasil's PIX manual.
+const pixExample = "00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-4266554400005204000053039865802BR5913Fulano de Tal6008BRASILIA62070503***63041D3D"
+
+func TestCRC16(t *testing.T) {
+	if crc := crc16("123456789"); crc != 0x29B1 {
+		t.Errorf("crc16(123456789) = %#04x, want 0x29b1", crc)
+	}
+}
+
+func testEMV() *EMV {
+	return &EMV{Fields: []EMVField{
+		{ID: EMVFormat, Value: "01"},
+		{ID: EMVInitiation, Value: "12"},
+		{ID: 4, Value: "4000123456789012"},
+		{ID: 26, Fields: []EMVField{{ID: 0, Value: "com.example.pay"}, {ID: 1, Value: "merchant-42"}}},
+		{ID: EMVCategory, Value: "5812"},
+		{ID: EMVCurrency, Value: "702"},
+		{ID: EMVAmount, Value: "12.50"},
+		{ID: EMVTip, Value: "02"},
+		{ID: EMVFixedFee, Value: "1.00"},
+		{ID: EMVCountry, Value: "SG"},
+		{ID: EMVName, Value: "Noodle House"},
+		{ID: EMVCity, Value: "Singapore"},
+		{ID: EMVPostalCode, Value: "018956"},
+		{ID: EMVAdditional, Fields: []EMVField{{ID: 1, Value: "INV-1001"}, {ID: 7, Value: "T1"}}},
+		{ID: EMVLanguage, Fields: []EMVField{{ID: 0, Value: "ZH"}, {ID: 1, Value: "面馆"}, {ID: 2, Value: "新加坡"}}},
+		{ID: 80, Fields: []EMVField{{ID: 0, Value: "com.example.loyalty"}, {ID: 1, Value: "yes"}}},
+	}}
+}
+
+func TestEMV(t *testing.T) {
+	e := testEMV()
+	if err := e.Check(); err != nil {
+		t.Fatal(err)
+	}
+	text := e.String()
+	if !strings.HasPrefix(text, "000201010212041640001234567890122634") {
+		t.Errorf("String() = %s", text)
+	}
+	if !strings.Contains(text, "64190002ZH0102面馆0203新加坡") {
+		t.Errorf("String() = %s: language template lengths should count characters", text)
+	}
+
+	got, err := ParseEMV(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, e) {
+		t.Errorf("ParseEMV(%s) =\n%+v\nwant\n%+v", text, got, e)
+	}
+	if got.String() != text {
+		t.Errorf("ParseEMV(%s).String() = %s", text, got.String())
+	}
+
+	// String sorts the fields by ID.
+	e.Fields[0], e.Fields[len(e.Fields)-1] = e.Fields[len(e.Fields)-1], e.Fields[0]
+	if e.String() != text {
+		t.Errorf("String() depends on field order")
+	}
+}
+
+func TestEMVCheck(t *testing.T) {
+	for _, tt := range []struct {
+		edit func(*EMV)
+		err  string
+	}{
+		{func(e *EMV) { e.Field(EMVFormat).Value = "02" }, "field 00"},
+		{func(e *EMV) { e.Field(EMVInitiation).Value = "13" }, "field 01"},
+		{func(e *EMV) { e.Field(EMVCategory).Value = "581" }, "field 52"},
+		{func(e *EMV) { e.Field(EMVCurrency).Value = "SGD" }, "field 53"},
+		{func(e *EMV) { e.Field(EMVAmount).Value = "1,50" }, "field 54"},
+		{func(e *EMV) { e.Field(EMVAmount).Value = "0.00" }, "field 54"},
+		{func(e *EMV) { e.Field(EMVAmount).Value = "12345678901.00" }, "field 54"},
+		{func(e *EMV) { e.Field(EMVTip).Value = "03" }, "goes with tip indicator"},
+		{func(e *EMV) { e.Field(EMVCountry).Value = "sg" }, "field 58"},
+		{func(e *EMV) { e.Field(EMVName).Value = strings.Repeat("x", 26) }, "field 59"},
+		{func(e *EMV) { e.Field(EMVName).Value = "Café" }, "invalid character"},
+		{func(e *EMV) { e.Field(EMVCity).Value = "" }, "field 60"},
+		{func(e *EMV) { e.Field(EMVPostalCode).Value = strings.Repeat("1", 11) }, "field 61"},
+		{func(e *EMV) { e.Field(4).Value = strings.Repeat("1", 100) }, "more than 99"},
+		{func(e *EMV) { e.Field(26).Fields[0].ID = 3 }, "globally unique identifier"},
+		{func(e *EMV) { e.Field(26).Fields[1].Value = strings.Repeat("x", 80) }, "template 26 has"},
+		{func(e *EMV) { e.Field(26).Fields = nil }, "no nested fields"},
+		{func(e *EMV) { e.Field(26).Fields[1].Fields = []EMVField{} }, "not a template"},
+		{func(e *EMV) { e.Field(EMVAdditional).Fields[0].Value = strings.Repeat("x", 26) }, "longer than 25"},
+		{func(e *EMV) { e.Field(EMVLanguage).Fields = e.Field(EMVLanguage).Fields[:1] }, "language and a name"},
+		{func(e *EMV) { e.Field(EMVCategory).Fields = []EMVField{} }, "not a template"},
+		{func(e *EMV) { e.Fields = append(e.Fields, EMVField{ID: EMVCRC, Value: "0000"}) }, "invalid field ID"},
+		{func(e *EMV) { e.Fields = append(e.Fields, EMVField{ID: EMVName, Value: "x"}) }, "duplicate field 59"},
+		{func(e *EMV) { e.Fields = e.Fields[1:] }, "missing required field 00"},
+		{func(e *EMV) {
+			e.Fields = append(e.Fields[:2], e.Fields[4:]...)
+		}, "missing merchant account"},
+		{func(e *EMV) {
+			for id := 81; id < 90; id++ {
+				e.Fields = append(e.Fields, EMVField{ID: id, Fields: []EMVField{{ID: 0, Value: strings.Repeat("x", 60)}}})
+			}
+		}, "more than 512"},
+	} {
+		e := testEMV()
+		tt.edit(e)
+		err := e.Check()
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Check() = %v, want error containing %q", err, tt.err)
+		}
+		if _, err := e.Encode(qr.M); err == nil {
+			t.Errorf("Encode succeeded, want error containing %q", tt.err)
+		}
+	}
+}
+
+func TestParseEMV(t *testing.T) {
+	if _, err := ParseEMV(pixExample); err != nil {
+		t.Errorf("ParseEMV(pixExample): %v", err)
+	}
+	// Lower-case CRC digits are accepted.
+	if _, err := ParseEMV(pixExample[:len(pixExample)-4] + "1d3d"); err != nil {
+		t.Errorf("ParseEMV with lower-case CRC: %v", err)
+	}
+
+	withCRC := func(s string) string {
+		s += "6304"
+		return s + fmt.Sprintf("%04X", crc16(s))
+	}
+	for _, text := range []string{
+		"",
+		"6304",
+		pixExample[:len(pixExample)-1] + "E",
+		strings.Replace(pixExample, "Fulano", "Fulana", 1),
+		withCRC("000201"),
+		withCRC("0002015204000053039865802BR5913Fulano de Tal6008BRASILIA"),
+		withCRC("5204000000020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-42665544000053039865802BR5913Fulano de Tal6008BRASILIA"),
+		withCRC("00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-4266554400005204000053039865802BR5913Fulano de Tal6008BRASILIA62"),
+		withCRC("00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-4266554400005204000053039865802BR5913Fulano de Tal6099BRASILIA"),
+		withCRC("0002012605xxxxx5204000053039865802BR5913Fulano de Tal6008BRASILIA"),
+	} {
+		if e, err := ParseEMV(text); err == nil {
+			t.Errorf("ParseEMV(%q) = %+v, want error", text, e)
+		}
+	}
+}
+
+func TestPIX(t *testing.T) {
+	p := PIX{Key: "123e4567-e12b-12d1-a456-426655440000", Name: "Fulano de Tal", City: "BRASILIA"}
+	if s := p.String(); s != pixExample {
+		t.Errorf("String() =\n%s\nwant\n%s", s, pixExample)
+	}
+
+	for _, p := range []PIX{
+		p,
+		{Key: "+5561912345678", Description: "Pagamento do pedido", Name: "Loja", City: "SAO PAULO", Amount: "10.50", TxID: "PEDIDO123"},
+		{Key: "fulano@example.com", Name: "Fulano", City: "RIO", Amount: "0.01"},
+	} {
+		if err := p.Check(); err != nil {
+			t.Errorf("%+v: Check: %v", p, err)
+			continue
+		}
+		got, err := ParsePIX(p.String())
+		if err != nil {
+			t.Errorf("ParsePIX(%s): %v", p.String(), err)
+			continue
+		}
+		if *got != p {
+			t.Errorf("ParsePIX(%s) = %+v, want %+v", p.String(), *got, p)
+		}
+	}
+
+	for _, bad := range []PIX{
+		{Name: "x", City: "y"},
+		{Key: strings.Repeat("k", 78), Name: "x", City: "y"},
+		{Key: "k", Name: "x", City: "y", Amount: "1.001"},
+		{Key: "k", Name: "x", City: "y", TxID: "with-dash"},
+		{Key: "k", Name: "x", City: "y", TxID: strings.Repeat("1", 26)},
+		{Key: "k", Name: "São João", City: "y"},
+		{Key: "k", City: "y"},
+		{Key: "k", Description: strings.Repeat("d", 80), Name: "x", City: "y"},
+	} {
+		if _, err := bad.Encode(qr.M); err == nil {
+			t.Errorf("%+v: Encode succeeded, want error", bad)
+		}
+	}
+
+	e := testEMV()
+	if p, err := ParsePIX(e.String()); err == nil {
+		t.Errorf("ParsePIX(non-PIX) = %+v, want error", p)
+	}
+}
+
+func TestUPI(t *testing.T) {
+	u := UPI{Address: "shop.42@okbank", Name: "Chai & Co", Amount: "25.00", Note: "order #7", Ref: "ORD7", Category: "5814"}
+	want := "upi://pay?pa=shop.42%40okbank&pn=Chai%20%26%20Co&mc=5814&tr=ORD7&tn=order%20%237&am=25.00&cu=INR"
+	if s := u.String(); s != want {
+		t.Errorf("String() =\n%s\nwant\n%s", s, want)
+	}
+	got, err := ParseUPI(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != u {
+		t.Errorf("ParseUPI = %+v, want %+v", *got, u)
+	}
+
+	// Other apps write + for spaces and leave out the currency.
+	got, err = ParseUPI("UPI://pay?pn=Chai+Stall&pa=chai@upi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != (UPI{Address: "chai@upi", Name: "Chai Stall"}) {
+		t.Errorf("ParseUPI = %+v", *got)
+	}
+
+	for _, text := range []string{
+		"",
+		"https://pay?pa=a@b&pn=x",
+		"upi://collect?pa=a@b&pn=x",
+		"upi://pay?pa=a@b&pn=x&cu=USD",
+		"upi://pay?pa=ab&pn=x",
+		"upi://pay?pa=a@b@c&pn=x",
+		"upi://pay?pa=a b@c&pn=x",
+		"upi://pay?pa=a@b",
+		"upi://pay?pa=a@b&pn=x&am=1.234",
+		"upi://pay?pa=a@b&pn=x&mc=12",
+		"upi://pay?pa=a@b&pn=x&am=%zz",
+	} {
+		if u, err := ParseUPI(text); err == nil {
+			t.Errorf("ParseUPI(%q) = %+v, want error", text, *u)
+		}
+	}
+}
+
+func TestPaymentEncode(t *testing.T) {
+	p := PIX{Key: "+5561912345678", Name: "Loja", City: "SAO PAULO", Amount: "10.50", TxID: "PEDIDO123"}
+	u := UPI{Address: "shop@okbank", Name: "Shop", Amount: "99"}
+	for _, tt := range []struct {
+		encode func(qr.Level) (*qr.Code, error)
+		text   string
+	}{
+		{p.Encode, p.String()},
+		{u.Encode, u.String()},
+		{testEMV().Encode, testEMV().String()},
+	} {
+		c, err := tt.encode(qr.Q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := png.Decode(bytes.NewReader(c.PNG()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := decode.Decode(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Text != tt.text {
+			t.Errorf("decoded %q, want %q", r.Text, tt.text)
+		}
+	}
+}
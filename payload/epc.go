@@ -0,0 +1,209 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"rsc.io/qr"
+)
+
+// A GiroCode describes a SEPA credit transfer in euros,
+// in the EPC069-12 format, also known as GiroCode or EPC QR code:
+// lines of text starting with "BCD", which banking apps
+// scan to fill in a payment.
+//
+// The text always uses the UTF-8 character set.
+type GiroCode struct {
+	Name string // beneficiary name, at most 70 characters
+	IBAN string // beneficiary account; spaces are ignored
+
+	// BIC is the beneficiary's bank. It may be empty
+	// for payments within the European Economic Area.
+	BIC string
+
+	// Amount is the amount in euros, such as "12.30",
+	// from 0.01 to 999999999.99 with at most two decimals.
+	// The empty string leaves the amount for the payer to enter.
+	Amount string
+
+	Purpose string // optional ISO 20022 purpose code, such as "CHAR"
+
+	// The remittance information is at most one of
+	// Reference, an ISO 11649 creditor reference such as "RF18539007547034",
+	// or Text, a message of at most 140 characters.
+	Reference string
+	Text      string
+
+	Info string // optional note to the payer, at most 70 characters
+}
+
+// maxGiroCode is the longest allowed GiroCode text, in bytes.
+const maxGiroCode = 331
+
+// Check reports whether g describes a valid transfer.
+func (g *GiroCode) Check() error {
+	if g.Name == "" {
+		return errors.New("epc: missing beneficiary name")
+	}
+	if _, err := normalizeIBAN(g.IBAN); err != nil {
+		return fmt.Errorf("epc: %v", err)
+	}
+	if g.BIC != "" {
+		if err := checkBIC(g.BIC); err != nil {
+			return fmt.Errorf("epc: %v", err)
+		}
+	}
+	if g.Amount != "" {
+		if err := checkAmount(g.Amount); err != nil {
+			return fmt.Errorf("epc: %v", err)
+		}
+	}
+	if g.Purpose != "" && (len(g.Purpose) != 4 || !isUpper(g.Purpose)) {
+		return fmt.Errorf("epc: purpose code %q is not four upper-case letters", g.Purpose)
+	}
+	if g.Reference != "" && g.Text != "" {
+		return errors.New("epc: cannot have both a creditor reference and a remittance text")
+	}
+	if g.Reference != "" {
+		if err := checkRF(g.Reference); err != nil {
+			return fmt.Errorf("epc: %v", err)
+		}
+	}
+	for _, f := range []struct {
+		name string
+		val  string
+		max  int
+	}{
+		{"beneficiary name", g.Name, 70},
+		{"remittance text", g.Text, 140},
+		{"beneficiary information", g.Info, 70},
+	} {
+		if n := utf8.RuneCountInString(f.val); n > f.max {
+			return fmt.Errorf("epc: %s has %d characters, more than %d", f.name, n, f.max)
+		}
+		if !utf8.ValidString(f.val) || strings.IndexFunc(f.val, unicode.IsControl) >= 0 {
+			return fmt.Errorf("epc: %s contains invalid characters", f.name)
+		}
+	}
+	if n := len(g.String()); n > maxGiroCode {
+		return fmt.Errorf("epc: payload has %d bytes, more than %d", n, maxGiroCode)
+	}
+	return nil
+}
+
+// String returns the GiroCode text for g.
+// It does not check that g is valid; see Check.
+//
+// The text uses version 001 of the format when g has a BIC,
+// for the benefit of older readers, and version 002 otherwise.
+func (g *GiroCode) String() string {
+	version := "002"
+	if g.BIC != "" {
+		version = "001"
+	}
+	iban, err := normalizeIBAN(g.IBAN)
+	if err != nil {
+		iban = g.IBAN
+	}
+	amount := ""
+	if g.Amount != "" {
+		amount = "EUR" + g.Amount
+	}
+	lines := []string{
+		"BCD",
+		version,
+		"1", // UTF-8
+		"SCT",
+		g.BIC,
+		g.Name,
+		iban,
+		amount,
+		g.Purpose,
+		g.Reference,
+		g.Text,
+		g.Info,
+	}
+	// Trailing empty lines may be left out.
+	for lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Encode returns g encoded as a QR code.
+// The code always uses level M, as the standard requires.
+func (g *GiroCode) Encode() (*qr.Code, error) {
+	if err := g.Check(); err != nil {
+		return nil, err
+	}
+	return qr.Encode(g.String(), qr.M)
+}
+
+// ParseGiroCode parses GiroCode text, as returned by String.
+// It accepts the UTF-8 and ISO 8859-1 character sets.
+func ParseGiroCode(text string) (*GiroCode, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	if len(lines) < 7 || lines[0] != "BCD" {
+		return nil, errors.New("epc: not a GiroCode")
+	}
+	switch lines[1] {
+	case "001", "002":
+	default:
+		return nil, fmt.Errorf("epc: unsupported version %q", lines[1])
+	}
+	switch lines[2] {
+	case "1":
+	case "2":
+		lines = strings.Split(latin1(text), "\n")
+	default:
+		return nil, fmt.Errorf("epc: unsupported character set %q", lines[2])
+	}
+	if lines[3] != "SCT" {
+		return nil, fmt.Errorf("epc: unsupported identification code %q", lines[3])
+	}
+	if len(lines) > 12 {
+		return nil, errors.New("epc: too many lines")
+	}
+	for len(lines) < 12 {
+		lines = append(lines, "")
+	}
+	g := &GiroCode{
+		BIC:       lines[4],
+		Name:      lines[5],
+		IBAN:      lines[6],
+		Purpose:   lines[8],
+		Reference: lines[9],
+		Text:      lines[10],
+		Info:      lines[11],
+	}
+	if a := lines[7]; a != "" {
+		if !strings.HasPrefix(a, "EUR") {
+			return nil, fmt.Errorf("epc: amount %q not in euros", a)
+		}
+		g.Amount = a[len("EUR"):]
+	}
+	if lines[1] == "001" && g.BIC == "" {
+		return nil, errors.New("epc: version 001 requires a BIC")
+	}
+	if err := g.Check(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// latin1 converts s from ISO 8859-1 to UTF-8.
+func latin1(s string) string {
+	r := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		r[i] = rune(s[i])
+	}
+	return string(r)
+}
@@ -0,0 +1,144 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"rsc.io/qr"
+	"rsc.io/qr/decode"
+)
+
+var giroTests = []struct {
+	g    GiroCode
+	text string
+}{
+	{
+		GiroCode{Name: "Red Cross of Belgium", IBAN: "BE72 0000 0000 1616", BIC: "BPOTBEB1", Amount: "1", Purpose: "CHAR", Text: "Urgency fund"},
+		"BCD\n001\n1\nSCT\nBPOTBEB1\nRed Cross of Belgium\nBE72000000001616\nEUR1\nCHAR\n\nUrgency fund",
+	},
+	{
+		GiroCode{Name: "Müller GmbH", IBAN: "de89370400440532013000", Amount: "999999999.99", Reference: "RF18539007547034", Info: "Danke!"},
+		"BCD\n002\n1\nSCT\n\nMüller GmbH\nDE89370400440532013000\nEUR999999999.99\n\nRF18539007547034\n\nDanke!",
+	},
+	{
+		GiroCode{Name: "Open amount", IBAN: "DE89370400440532013000"},
+		"BCD\n002\n1\nSCT\n\nOpen amount\nDE89370400440532013000",
+	},
+}
+
+func TestGiroCode(t *testing.T) {
+	for _, tt := range giroTests {
+		if err := tt.g.Check(); err != nil {
+			t.Errorf("%+v: Check: %v", tt.g, err)
+		}
+		if s := tt.g.String(); s != tt.text {
+			t.Errorf("%+v: String() = %q, want %q", tt.g, s, tt.text)
+		}
+		g, err := ParseGiroCode(tt.text)
+		if err != nil {
+			t.Errorf("ParseGiroCode(%q): %v", tt.text, err)
+			continue
+		}
+		if g.String() != tt.text {
+			t.Errorf("ParseGiroCode(%q).String() = %q", tt.text, g.String())
+		}
+	}
+}
+
+func TestGiroCodeCheck(t *testing.T) {
+	ok := GiroCode{Name: "x", IBAN: "DE89370400440532013000"}
+	for _, tt := range []struct {
+		edit func(*GiroCode)
+		err  string
+	}{
+		{func(g *GiroCode) { g.Name = "" }, "missing beneficiary name"},
+		{func(g *GiroCode) { g.Name = strings.Repeat("ä", 71) }, "beneficiary name has 71 characters"},
+		{func(g *GiroCode) { g.Name = "a\nb" }, "invalid characters"},
+		{func(g *GiroCode) { g.IBAN = "DE88370400440532013000" }, "check digits"},
+		{func(g *GiroCode) { g.IBAN = "DE8937040044" }, "15 to 34"},
+		{func(g *GiroCode) { g.IBAN = "D989370400440532013000" }, "malformed IBAN"},
+		{func(g *GiroCode) { g.BIC = "COBADE" }, "malformed BIC"},
+		{func(g *GiroCode) { g.BIC = "cobadeff" }, "malformed BIC"},
+		{func(g *GiroCode) { g.Amount = "0.00" }, "at least 0.01"},
+		{func(g *GiroCode) { g.Amount = "1.234" }, "two decimals"},
+		{func(g *GiroCode) { g.Amount = "1." }, "two decimals"},
+		{func(g *GiroCode) { g.Amount = "1000000000" }, "malformed amount"},
+		{func(g *GiroCode) { g.Amount = "1,50" }, "malformed amount"},
+		{func(g *GiroCode) { g.Amount = "-1" }, "malformed amount"},
+		{func(g *GiroCode) { g.Purpose = "char" }, "purpose code"},
+		{func(g *GiroCode) { g.Reference, g.Text = "RF18539007547034", "x" }, "both"},
+		{func(g *GiroCode) { g.Reference = "RF19539007547034" }, "check digits"},
+		{func(g *GiroCode) { g.Reference = "539007547034" }, "malformed creditor reference"},
+		{func(g *GiroCode) { g.Text = strings.Repeat("x", 141) }, "remittance text has 141"},
+		{func(g *GiroCode) { g.Info = strings.Repeat("x", 71) }, "beneficiary information has 71"},
+		{func(g *GiroCode) {
+			g.Name = strings.Repeat("€", 70)
+			g.Text = strings.Repeat("€", 40)
+		}, "more than 331"},
+	} {
+		g := ok
+		tt.edit(&g)
+		err := g.Check()
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%+v: Check() = %v, want error containing %q", g, err, tt.err)
+		}
+		if _, err := g.Encode(); err == nil {
+			t.Errorf("%+v: Encode succeeded, want error", g)
+		}
+	}
+}
+
+func TestParseGiroCode(t *testing.T) {
+	// ISO 8859-1 text and CRLF line endings.
+	g, err := ParseGiroCode("BCD\r\n002\r\n2\r\nSCT\r\n\r\nM\xfcller\r\nDE89370400440532013000\r\nEUR5.5\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Name != "Müller" || g.Amount != "5.5" {
+		t.Errorf("ParseGiroCode = %+v", *g)
+	}
+
+	for _, text := range []string{
+		"",
+		"BCD\n002\n1\nSCT\n\nx",
+		"ABC\n002\n1\nSCT\n\nx\nDE89370400440532013000",
+		"BCD\n003\n1\nSCT\n\nx\nDE89370400440532013000",
+		"BCD\n002\n9\nSCT\n\nx\nDE89370400440532013000",
+		"BCD\n002\n1\nINST\n\nx\nDE89370400440532013000",
+		"BCD\n001\n1\nSCT\n\nx\nDE89370400440532013000",
+		"BCD\n002\n1\nSCT\n\nx\nDE89370400440532013000\nUSD1",
+		"BCD\n002\n1\nSCT\n\nx\nDE89370400440532013000\n\n\n\n\n\nextra",
+	} {
+		if g, err := ParseGiroCode(text); err == nil {
+			t.Errorf("ParseGiroCode(%q) = %+v, want error", text, *g)
+		}
+	}
+}
+
+func TestGiroCodeEncode(t *testing.T) {
+	g := giroTests[1].g
+	c, err := g.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := png.Decode(bytes.NewReader(c.PNG()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := decode.Decode(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Level != qr.M {
+		t.Errorf("level %v, want M", r.Level)
+	}
+	if r.Text != g.String() {
+		t.Errorf("decoded %q, want %q", r.Text, g.String())
+	}
+}
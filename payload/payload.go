@@ -0,0 +1,76 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package payload builds and parses the text of common kinds of
+// QR codes, such as Wi-Fi network configurations, contact cards,
+// and payment requests.
+//
+// Each payload type has a String method returning the text to encode,
+// an Encode method encoding that text as a QR code, and a Parse
+// function turning scanned text back into the payload type.
+package payload // import "rsc.io/qr/payload"
+
+import "strings"
+
+// escape returns s with a backslash before
+// each byte that appears in special.
+func escape(s, special string) string {
+	if !strings.ContainsAny(s, special) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(special, s[i]) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// unescape returns s with backslash escapes removed:
+// a backslash followed by any byte stands for that byte.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// split splits s at each sep byte not escaped by a backslash.
+// The fields keep their escapes.
+func split(s string, sep byte) []string {
+	var fields []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			fields = append(fields, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(fields, s[start:])
+}
+
+// cut splits s around the first sep byte not escaped by a backslash.
+func cut(s string, sep byte) (before, after string, found bool) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
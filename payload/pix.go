@@ -0,0 +1,144 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"rsc.io/qr"
+)
+
+// A PIX describes a static payment request for PIX,
+// Brazil's instant payment system, as an EMV payload
+// with a br.gov.bcb.pix merchant account template.
+type PIX struct {
+	// Key is the receiver's PIX key: a CPF or CNPJ number,
+	// a phone number such as "+5561912345678", an email address,
+	// or a random key.
+	Key         string
+	Description string // optional message to the payer
+
+	Name   string // receiver name, at most 25 ASCII characters
+	City   string // receiver city, at most 15 ASCII characters
+	Amount string // amount in reais, such as "10.50"; empty lets the payer choose
+
+	// TxID identifies the transaction, in at most 25 letters and digits.
+	// The empty string means none.
+	TxID string
+}
+
+const (
+	pixGUI      = "br.gov.bcb.pix"
+	pixCurrency = "986" // Brazilian real
+	pixNoTxID   = "***"
+)
+
+// EMV returns the EMV payload for p.
+func (p *PIX) EMV() *EMV {
+	account := []EMVField{{ID: 0, Value: pixGUI}, {ID: 1, Value: p.Key}}
+	if p.Description != "" {
+		account = append(account, EMVField{ID: 2, Value: p.Description})
+	}
+	txid := p.TxID
+	if txid == "" {
+		txid = pixNoTxID
+	}
+	e := &EMV{Fields: []EMVField{
+		{ID: EMVFormat, Value: "01"},
+		{ID: 26, Fields: account},
+		{ID: EMVCategory, Value: "0000"},
+		{ID: EMVCurrency, Value: pixCurrency},
+		{ID: EMVCountry, Value: "BR"},
+		{ID: EMVName, Value: p.Name},
+		{ID: EMVCity, Value: p.City},
+		{ID: EMVAdditional, Fields: []EMVField{{ID: 5, Value: txid}}},
+	}}
+	if p.Amount != "" {
+		e.Fields = append(e.Fields, EMVField{ID: EMVAmount, Value: p.Amount})
+	}
+	return e
+}
+
+// Check reports whether p describes a valid payment request.
+func (p *PIX) Check() error {
+	if p.Key == "" {
+		return errors.New("pix: missing key")
+	}
+	if utf8.RuneCountInString(p.Key) > 77 {
+		return errors.New("pix: key longer than 77 characters")
+	}
+	if p.Amount != "" {
+		if err := checkAmount(p.Amount); err != nil {
+			return fmt.Errorf("pix: %v", err)
+		}
+	}
+	if p.TxID != "" && (len(p.TxID) > 25 || !isAlnum(strings.ToUpper(p.TxID))) {
+		return fmt.Errorf("pix: transaction ID %q is not 1 to 25 letters and digits", p.TxID)
+	}
+	return p.EMV().Check()
+}
+
+// String returns the EMV text for p.
+// It does not check that p is valid; see Check.
+func (p *PIX) String() string {
+	return p.EMV().String()
+}
+
+// Encode returns p encoded as a QR code at level l.
+func (p *PIX) Encode(l qr.Level) (*qr.Code, error) {
+	if err := p.Check(); err != nil {
+		return nil, err
+	}
+	return qr.Encode(p.String(), l)
+}
+
+// ParsePIX parses the EMV text of a static PIX payment request.
+func ParsePIX(text string) (*PIX, error) {
+	e, err := ParseEMV(text)
+	if err != nil {
+		return nil, err
+	}
+	var account *EMVField
+	for i := range e.Fields {
+		f := &e.Fields[i]
+		if gui := f.Field(0); 26 <= f.ID && f.ID <= 51 && gui != nil && strings.EqualFold(gui.Value, pixGUI) {
+			account = f
+			break
+		}
+	}
+	if account == nil {
+		return nil, errors.New("pix: missing br.gov.bcb.pix merchant account")
+	}
+	if e.Field(EMVCurrency).Value != pixCurrency || e.Field(EMVCountry).Value != "BR" {
+		return nil, errors.New("pix: payment not in Brazilian reais")
+	}
+	p := &PIX{
+		Name: e.Field(EMVName).Value,
+		City: e.Field(EMVCity).Value,
+	}
+	if f := account.Field(1); f != nil {
+		p.Key = f.Value
+	} else if account.Field(25) != nil {
+		return nil, errors.New("pix: dynamic payment requests are not supported")
+	}
+	if f := account.Field(2); f != nil {
+		p.Description = f.Value
+	}
+	if f := e.Field(EMVAmount); f != nil {
+		p.Amount = f.Value
+	}
+	if f := e.Field(EMVAdditional); f != nil {
+		if txid := f.Field(5); txid != nil && txid.Value != pixNoTxID {
+			p.TxID = txid.Value
+		}
+	}
+	if err := p.Check(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
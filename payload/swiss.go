@@ -0,0 +1,324 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"rsc.io/qr"
+)
+
+// A SwissBill describes the payment part of a Swiss QR-bill,
+// in the SPC format, version 0200, of the Swiss Payment Standards.
+//
+// The Swiss standards require the code to be printed with a
+// Swiss cross in its center at exactly 46 × 46 mm;
+// see the PNG and SVG methods.
+type SwissBill struct {
+	// Account is the creditor's IBAN or QR-IBAN,
+	// which must be Swiss or Liechtenstein. Spaces are ignored.
+	Account  string
+	Creditor SwissAddress
+
+	// Amount is the amount, such as "1949.75",
+	// from 0.01 to 999999999.99 with at most two decimals.
+	// The empty string leaves the amount for the payer to enter.
+	Amount   string
+	Currency string // "CHF" or "EUR"
+
+	Debtor SwissAddress // optional
+
+	// Reference is the payment reference.
+	// A QR-IBAN Account needs a 27-digit QR reference;
+	// any other IBAN allows an ISO 11649 creditor reference,
+	// such as "RF18539007547034", or no reference at all.
+	// Spaces are ignored.
+	Reference string
+
+	Message  string   // optional unstructured message
+	BillInfo string   // optional structured bill information
+	AltPmt   []string // at most two alternative payment procedures
+}
+
+// A SwissAddress is a structured address in a Swiss QR-bill.
+type SwissAddress struct {
+	Name           string // at most 70 characters
+	Street         string // at most 70 characters
+	BuildingNumber string // at most 16 characters
+	PostalCode     string // at most 16 characters, without country code prefix
+	Town           string // at most 35 characters
+	Country        string // ISO 3166-1 two-letter code, such as "CH"
+}
+
+const (
+	maxSwissBill = 997 // longest allowed SPC text, in characters
+	maxSwissVer  = 25  // largest allowed QR version
+)
+
+// isQRIBAN reports whether the normalized IBAN is a QR-IBAN,
+// which has an institution identifier from 30000 to 31999.
+func isQRIBAN(iban string) bool {
+	return len(iban) == 21 && (iban[4:6] == "30" || iban[4:6] == "31")
+}
+
+// normalizeRef returns the reference r with spaces removed.
+func normalizeRef(r string) string {
+	return strings.ReplaceAll(r, " ", "")
+}
+
+// refType returns the SPC reference type for b.
+func (b *SwissBill) refType() string {
+	ref := normalizeRef(b.Reference)
+	switch {
+	case ref == "":
+		return "NON"
+	case strings.HasPrefix(ref, "RF"):
+		return "SCOR"
+	}
+	return "QRR"
+}
+
+// Check reports whether b describes a valid bill.
+func (b *SwissBill) Check() error {
+	iban, err := normalizeIBAN(b.Account)
+	if err != nil {
+		return fmt.Errorf("swiss: %v", err)
+	}
+	if (iban[:2] != "CH" && iban[:2] != "LI") || len(iban) != 21 {
+		return fmt.Errorf("swiss: account %s is not a Swiss or Liechtenstein IBAN", iban)
+	}
+	if err := b.Creditor.check("creditor"); err != nil {
+		return err
+	}
+	if b.Debtor != (SwissAddress{}) {
+		if err := b.Debtor.check("debtor"); err != nil {
+			return err
+		}
+	}
+	if b.Amount != "" {
+		if err := checkAmount(b.Amount); err != nil {
+			return fmt.Errorf("swiss: %v", err)
+		}
+	}
+	if b.Currency != "CHF" && b.Currency != "EUR" {
+		return fmt.Errorf("swiss: currency %q is not CHF or EUR", b.Currency)
+	}
+
+	ref := normalizeRef(b.Reference)
+	switch b.refType() {
+	case "QRR":
+		if err := checkQRReference(ref); err != nil {
+			return err
+		}
+		if !isQRIBAN(iban) {
+			return errors.New("swiss: QR reference needs a QR-IBAN account")
+		}
+	case "SCOR":
+		if err := checkRF(ref); err != nil {
+			return fmt.Errorf("swiss: %v", err)
+		}
+	}
+	if isQRIBAN(iban) && b.refType() != "QRR" {
+		return errors.New("swiss: QR-IBAN account needs a QR reference")
+	}
+
+	if n := utf8.RuneCountInString(b.Message + b.BillInfo); n > 140 {
+		return fmt.Errorf("swiss: message and bill information have %d characters, more than 140", n)
+	}
+	if len(b.AltPmt) > 2 {
+		return errors.New("swiss: more than two alternative payment procedures")
+	}
+	for _, a := range b.AltPmt {
+		if err := checkSwissText("alternative payment procedure", a, 100); err != nil {
+			return err
+		}
+	}
+	if err := checkSwissText("message", b.Message, 140); err != nil {
+		return err
+	}
+	if err := checkSwissText("bill information", b.BillInfo, 140); err != nil {
+		return err
+	}
+	if n := utf8.RuneCountInString(b.String()); n > maxSwissBill {
+		return fmt.Errorf("swiss: payload has %d characters, more than %d", n, maxSwissBill)
+	}
+	return nil
+}
+
+func (a *SwissAddress) check(who string) error {
+	if a.Name == "" || a.PostalCode == "" || a.Town == "" {
+		return fmt.Errorf("swiss: %s address needs a name, postal code, and town", who)
+	}
+	if len(a.Country) != 2 || !isUpper(a.Country) {
+		return fmt.Errorf("swiss: %s country %q is not a two-letter code", who, a.Country)
+	}
+	for _, f := range []struct {
+		name string
+		val  string
+		max  int
+	}{
+		{"name", a.Name, 70},
+		{"street", a.Street, 70},
+		{"building number", a.BuildingNumber, 16},
+		{"postal code", a.PostalCode, 16},
+		{"town", a.Town, 35},
+	} {
+		if err := checkSwissText(who+" "+f.name, f.val, f.max); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSwissText checks that s has at most max characters,
+// all from the Latin character set the Swiss standards allow.
+func checkSwissText(name, s string, max int) error {
+	if n := utf8.RuneCountInString(s); n > max {
+		return fmt.Errorf("swiss: %s has %d characters, more than %d", name, n, max)
+	}
+	for _, r := range s {
+		switch {
+		case 0x20 <= r && r <= 0x7E,
+			0xA0 <= r && r <= 0x17F,
+			0x218 <= r && r <= 0x21B,
+			r == '€':
+			continue
+		}
+		return fmt.Errorf("swiss: %s contains invalid character %q", name, r)
+	}
+	return nil
+}
+
+// checkQRReference checks the 27-digit QR reference ref,
+// whose last digit is a recursive mod-10 check digit.
+func checkQRReference(ref string) error {
+	if len(ref) != 27 || !isDigits(ref) {
+		return errors.New("swiss: QR reference must have 27 digits")
+	}
+	if mod10(ref[:26]) != ref[26] {
+		return errors.New("swiss: QR reference check digit does not match")
+	}
+	return nil
+}
+
+// mod10 returns the recursive mod-10 check digit for the digits s.
+func mod10(s string) byte {
+	table := [10]int{0, 9, 4, 6, 8, 2, 7, 1, 3, 5}
+	carry := 0
+	for i := 0; i < len(s); i++ {
+		carry = table[(carry+int(s[i]-'0'))%10]
+	}
+	return byte('0' + (10-carry)%10)
+}
+
+// String returns the SPC text for b.
+// It does not check that b is valid; see Check.
+func (b *SwissBill) String() string {
+	iban, err := normalizeIBAN(b.Account)
+	if err != nil {
+		iban = b.Account
+	}
+	lines := []string{"SPC", "0200", "1", iban}
+	lines = append(lines, b.Creditor.lines()...)
+	lines = append(lines, "", "", "", "", "", "", "") // ultimate creditor, for future use
+	lines = append(lines, b.Amount, b.Currency)
+	lines = append(lines, b.Debtor.lines()...)
+	lines = append(lines, b.refType(), normalizeRef(b.Reference), b.Message, "EPD")
+	if b.BillInfo != "" || len(b.AltPmt) > 0 {
+		lines = append(lines, b.BillInfo)
+	}
+	lines = append(lines, b.AltPmt...)
+	return strings.Join(lines, "\n")
+}
+
+func (a *SwissAddress) lines() []string {
+	if *a == (SwissAddress{}) {
+		return []string{"", "", "", "", "", "", ""}
+	}
+	return []string{"S", a.Name, a.Street, a.BuildingNumber, a.PostalCode, a.Town, a.Country}
+}
+
+// Encode returns b encoded as a QR code at level M,
+// as the standard requires, without the Swiss cross.
+func (b *SwissBill) Encode() (*qr.Code, error) {
+	if err := b.Check(); err != nil {
+		return nil, err
+	}
+	c, _, err := qr.EncodeOptions(b.String(), qr.M, &qr.Options{MaxVersion: maxSwissVer})
+	return c, err
+}
+
+// ParseSwissBill parses SPC text, as returned by String.
+func ParseSwissBill(text string) (*SwissBill, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if len(lines) < 31 || lines[0] != "SPC" {
+		return nil, errors.New("swiss: not a Swiss QR-bill")
+	}
+	if !strings.HasPrefix(lines[1], "02") || len(lines[1]) != 4 {
+		return nil, fmt.Errorf("swiss: unsupported version %q", lines[1])
+	}
+	if lines[2] != "1" {
+		return nil, fmt.Errorf("swiss: unsupported coding type %q", lines[2])
+	}
+	if lines[30] != "EPD" {
+		return nil, errors.New("swiss: missing EPD trailer")
+	}
+	if len(lines) > 34 {
+		return nil, errors.New("swiss: too many lines")
+	}
+	for _, l := range lines[11:18] {
+		if l != "" {
+			return nil, errors.New("swiss: ultimate creditor must be empty")
+		}
+	}
+	b := &SwissBill{
+		Account:   lines[3],
+		Amount:    lines[18],
+		Currency:  lines[19],
+		Reference: lines[28],
+		Message:   lines[29],
+	}
+	var err error
+	if b.Creditor, err = parseSwissAddress(lines[4:11]); err != nil {
+		return nil, err
+	}
+	if b.Debtor, err = parseSwissAddress(lines[20:27]); err != nil {
+		return nil, err
+	}
+	if len(lines) > 31 {
+		b.BillInfo = lines[31]
+	}
+	if len(lines) > 32 {
+		b.AltPmt = lines[32:]
+	}
+	if t := lines[27]; t != b.refType() {
+		return nil, fmt.Errorf("swiss: reference type %q does not match reference", t)
+	}
+	if err := b.Check(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func parseSwissAddress(lines []string) (SwissAddress, error) {
+	switch lines[0] {
+	case "":
+		for _, l := range lines[1:] {
+			if l != "" {
+				return SwissAddress{}, errors.New("swiss: address without address type")
+			}
+		}
+		return SwissAddress{}, nil
+	case "S":
+		return SwissAddress{lines[1], lines[2], lines[3], lines[4], lines[5], lines[6]}, nil
+	case "K":
+		return SwissAddress{}, errors.New("swiss: combined addresses are not supported")
+	}
+	return SwissAddress{}, fmt.Errorf("swiss: unknown address type %q", lines[0])
+}
@@ -0,0 +1,255 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"rsc.io/qr"
+	"rsc.io/qr/decode"
+)
+
+var (
+	creditor = SwissAddress{Name: "Robert Schneider AG", Street: "Rue du Lac", BuildingNumber: "1268", PostalCode: "2501", Town: "Biel", Country: "CH"}
+	debtor   = SwissAddress{Name: "Pia-Maria Rutschmann-Schnyder", Street: "Grosse Marktgasse", BuildingNumber: "28", PostalCode: "9400", Town: "Rorschach", Country: "CH"}
+)
+
+var swissBills = []SwissBill{
+	{
+		Account:   "CH44 3199 9123 0008 8901 2",
+		Creditor:  creditor,
+		Amount:    "1949.75",
+		Currency:  "CHF",
+		Debtor:    debtor,
+		Reference: "21 00000 00003 13947 14300 09017",
+		Message:   "Auftrag vom 15.06.2020",
+		BillInfo:  "//S1/10/10201409/11/200701/20/140.000-53/30/102673831/31/200615/32/7.7/40/0:30",
+		AltPmt:    []string{"Name AV1: UV;UltraPay005;12345", "Name AV2: XY;XYService;54321"},
+	},
+	{
+		Account:   "CH5800791123000889012",
+		Creditor:  creditor,
+		Currency:  "EUR",
+		Reference: "RF18539007547034",
+	},
+	{
+		Account:  "CH58 0079 1123 0008 8901 2",
+		Creditor: SwissAddress{Name: "Zoë Müller", PostalCode: "9490", Town: "Vaduz", Country: "LI"},
+		Amount:   "50",
+		Currency: "CHF",
+		Message:  "Spende, merci €",
+	},
+}
+
+func TestSwissBill(t *testing.T) {
+	want := strings.Join([]string{
+		"SPC", "0200", "1", "CH4431999123000889012",
+		"S", "Robert Schneider AG", "Rue du Lac", "1268", "2501", "Biel", "CH",
+		"", "", "", "", "", "", "",
+		"1949.75", "CHF",
+		"S", "Pia-Maria Rutschmann-Schnyder", "Grosse Marktgasse", "28", "9400", "Rorschach", "CH",
+		"QRR", "210000000003139471430009017", "Auftrag vom 15.06.2020", "EPD",
+		"//S1/10/10201409/11/200701/20/140.000-53/30/102673831/31/200615/32/7.7/40/0:30",
+		"Name AV1: UV;UltraPay005;12345", "Name AV2: XY;XYService;54321",
+	}, "\n")
+	if s := swissBills[0].String(); s != want {
+		t.Errorf("String() =\n%s\nwant\n%s", s, want)
+	}
+	if s := swissBills[1].String(); !strings.HasSuffix(s, "\n\n\n\n\n\n\n\nSCOR\nRF18539007547034\n\nEPD") {
+		t.Errorf("String() without debtor =\n%s", s)
+	}
+
+	for _, b := range swissBills {
+		if err := b.Check(); err != nil {
+			t.Errorf("%+v: Check: %v", b, err)
+			continue
+		}
+		got, err := ParseSwissBill(b.String())
+		if err != nil {
+			t.Errorf("ParseSwissBill(%q): %v", b.String(), err)
+			continue
+		}
+		want := b
+		want.Account = strings.ReplaceAll(want.Account, " ", "")
+		want.Reference = strings.ReplaceAll(want.Reference, " ", "")
+		if !reflect.DeepEqual(*got, want) {
+			t.Errorf("ParseSwissBill(%q) =\n%+v\nwant\n%+v", b.String(), *got, want)
+		}
+	}
+}
+
+func TestSwissBillCheck(t *testing.T) {
+	for _, tt := range []struct {
+		edit func(*SwissBill)
+		err  string
+	}{
+		{func(b *SwissBill) { b.Account = "DE89370400440532013000" }, "not a Swiss"},
+		{func(b *SwissBill) { b.Account = "CH4431999123000889013" }, "check digits"},
+		{func(b *SwissBill) { b.Account = "CH5800791123000889012" }, "needs a QR-IBAN"},
+		{func(b *SwissBill) { b.Reference = "" }, "needs a QR reference"},
+		{func(b *SwissBill) { b.Reference = "RF18539007547034" }, "needs a QR reference"},
+		{func(b *SwissBill) { b.Reference = "210000000003139471430009018" }, "check digit"},
+		{func(b *SwissBill) { b.Reference = "2100000000031394714300090" }, "27 digits"},
+		{func(b *SwissBill) { b.Currency = "USD" }, "not CHF or EUR"},
+		{func(b *SwissBill) { b.Amount = "12.345" }, "two decimals"},
+		{func(b *SwissBill) { b.Amount = "0" }, "at least 0.01"},
+		{func(b *SwissBill) { b.Creditor.Town = "" }, "creditor address needs"},
+		{func(b *SwissBill) { b.Debtor.Country = "Schweiz" }, "debtor country"},
+		{func(b *SwissBill) { b.Creditor.Name = strings.Repeat("x", 71) }, "creditor name has 71"},
+		{func(b *SwissBill) { b.Creditor.Town = "Zürich 日本" }, "invalid character '日'"},
+		{func(b *SwissBill) { b.Message = "a\nb" }, "invalid character"},
+		{func(b *SwissBill) { b.BillInfo = strings.Repeat("x", 130) }, "more than 140"},
+		{func(b *SwissBill) { b.AltPmt = append(b.AltPmt, "third") }, "more than two"},
+		{func(b *SwissBill) { b.AltPmt = []string{strings.Repeat("x", 101)} }, "more than 100"},
+	} {
+		b := swissBills[0]
+		b.Creditor, b.Debtor = creditor, debtor
+		b.AltPmt = append([]string(nil), b.AltPmt...)
+		tt.edit(&b)
+		err := b.Check()
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%+v: Check() = %v, want error containing %q", b, err, tt.err)
+		}
+		if _, err := b.SVG(); err == nil {
+			t.Errorf("%+v: SVG succeeded, want error", b)
+		}
+	}
+
+	// SCOR and NON references need an ordinary IBAN.
+	b := swissBills[1]
+	b.Reference = "RF19539007547034"
+	if err := b.Check(); err == nil || !strings.Contains(err.Error(), "check digits") {
+		t.Errorf("bad SCOR: Check() = %v", err)
+	}
+}
+
+func TestParseSwissBill(t *testing.T) {
+	text := swissBills[1].String()
+	crlf := strings.ReplaceAll(text, "\n", "\r\n") + "\r\n"
+	if _, err := ParseSwissBill(crlf); err != nil {
+		t.Errorf("ParseSwissBill with CRLF: %v", err)
+	}
+
+	lines := strings.Split(text, "\n")
+	edit := func(i int, s string) string {
+		l := append([]string(nil), lines...)
+		l[i] = s
+		return strings.Join(l, "\n")
+	}
+	for _, text := range []string{
+		"",
+		strings.Join(lines[:30], "\n"),
+		edit(0, "SPD"),
+		edit(1, "0100"),
+		edit(2, "2"),
+		edit(4, "K"),
+		edit(4, "X"),
+		edit(11, "S"),
+		edit(21, "x"),
+		edit(27, "NON"),
+		edit(30, "END"),
+		text + "\n\nalt 1\nalt 2\nalt 3",
+	} {
+		if b, err := ParseSwissBill(text); err == nil {
+			t.Errorf("ParseSwissBill(%q) = %+v, want error", text, *b)
+		}
+	}
+}
+
+func TestQRReference(t *testing.T) {
+	for _, ref := range []string{
+		"210000000003139471430009017",
+		"000000000000000000000000000",
+	} {
+		if err := checkQRReference(ref); err != nil {
+			t.Errorf("checkQRReference(%s): %v", ref, err)
+		}
+	}
+}
+
+func TestSwissPNG(t *testing.T) {
+	b := swissBills[0]
+	data, err := b.PNG(300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 46 mm at 300 dpi.
+	if r := m.Bounds(); r.Dx() != 543 || r.Dy() != 543 {
+		t.Errorf("image is %v, want 543×543", r)
+	}
+	i := bytes.Index(data, []byte("pHYs"))
+	if i < 0 {
+		t.Fatalf("missing pHYs chunk")
+	}
+	if ppm := binary.BigEndian.Uint32(data[i+4:]); ppm != 11811 {
+		t.Errorf("pHYs %d pixels per meter, want 11811", ppm)
+	}
+
+	// The center has the white cross, 22 pixels from the center
+	// to the end of an arm, on a black square, 35 pixels from
+	// the center to an edge.
+	at := func(x, y int) uint32 {
+		r, _, _, _ := m.At(x, y).RGBA()
+		return r
+	}
+	if at(271, 271) == 0 || at(271, 271-30) != 0 || at(271-30, 271-30) != 0 {
+		t.Errorf("Swiss cross not drawn")
+	}
+
+	// It still scans once given a quiet zone.
+	padded := image.NewGray(image.Rect(0, 0, 543+2*60, 543+2*60))
+	draw.Draw(padded, padded.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(padded, m.Bounds().Add(image.Pt(60, 60)), m, image.Point{}, draw.Src)
+	r, err := decode.Decode(padded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Text != b.String() || r.Level != qr.M || r.Version > 25 {
+		t.Errorf("decoded version %d level %v %q", r.Version, r.Level, r.Text)
+	}
+
+	if _, err := b.PNG(0); err == nil {
+		t.Errorf("PNG(0) succeeded, want error")
+	}
+	if _, err := b.PNG(10); err == nil {
+		t.Errorf("PNG(10) succeeded, want error")
+	}
+}
+
+func TestSwissSVG(t *testing.T) {
+	data, err := swissBills[2].SVG()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		_, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid XML: %v", err)
+		}
+	}
+	if !bytes.Contains(data, []byte(`width="46mm" height="46mm"`)) {
+		t.Errorf("SVG not 46 mm")
+	}
+	if n := bytes.Count(data, []byte("<rect ")); n != 5 {
+		t.Errorf("%d rects, want background and 4 for the cross", n)
+	}
+}
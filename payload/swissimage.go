@@ -0,0 +1,158 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strconv"
+)
+
+// swissSize is the side of a QR-bill code, in millimeters,
+// not counting the quiet zone.
+const swissSize = 46.0
+
+// A mmRect is a rectangle in millimeters, filled black or white.
+type mmRect struct {
+	x, y, w, h float64
+	black      bool
+}
+
+func (r mmRect) contains(x, y float64) bool {
+	return r.x <= x && x < r.x+r.w && r.y <= y && y < r.y+r.h
+}
+
+// swissCross returns the Swiss cross overlay, centered on the code,
+// as rectangles to fill in order: a 7 mm white frame,
+// a 6 mm black square, and a white cross with the proportions
+// of the cross on the Swiss flag: arms 6/32 of the square wide
+// and a span of 20/32 of the square.
+func swissCross() []mmRect {
+	const (
+		c    = swissSize / 2
+		sq   = 6.0
+		arm  = sq * 6 / 32
+		span = sq * 20 / 32
+	)
+	return []mmRect{
+		{c - 3.5, c - 3.5, 7, 7, false},
+		{c - sq/2, c - sq/2, sq, sq, true},
+		{c - span/2, c - arm/2, span, arm, false},
+		{c - arm/2, c - span/2, arm, span, false},
+	}
+}
+
+// PNG returns a PNG image of b's code with the Swiss cross,
+// 46 × 46 mm at the given resolution in dots per inch,
+// which the image records so that it prints at that size.
+// As the standard requires, the image has no quiet zone:
+// the payment part of the bill leaves 5 mm free around it.
+func (b *SwissBill) PNG(dpi int) ([]byte, error) {
+	if dpi <= 0 {
+		return nil, fmt.Errorf("swiss: invalid resolution %d dpi", dpi)
+	}
+	c, err := b.Encode()
+	if err != nil {
+		return nil, err
+	}
+	px := int(math.Round(swissSize / 25.4 * float64(dpi)))
+	if px < c.Size {
+		return nil, fmt.Errorf("swiss: %d dpi too low for %d×%d code", dpi, c.Size, c.Size)
+	}
+	cross := swissCross()
+	m := image.NewGray(image.Rect(0, 0, px, px))
+	for y := 0; y < px; y++ {
+		for x := 0; x < px; x++ {
+			// Sample the module and cross at the pixel's center.
+			black := c.Black((2*x+1)*c.Size/(2*px), (2*y+1)*c.Size/(2*px))
+			mx := (float64(x) + 0.5) * swissSize / float64(px)
+			my := (float64(y) + 0.5) * swissSize / float64(px)
+			for _, r := range cross {
+				if r.contains(mx, my) {
+					black = r.black
+				}
+			}
+			if !black {
+				m.SetGray(x, y, color.Gray{0xFF})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		return nil, err
+	}
+	return withDPI(buf.Bytes(), dpi), nil
+}
+
+// withDPI returns the PNG data with a pHYs chunk
+// recording a resolution of dpi dots per inch.
+func withDPI(data []byte, dpi int) []byte {
+	ppm := uint32(math.Round(float64(dpi) / 0.0254))
+	chunk := make([]byte, 4+4+9+4)
+	binary.BigEndian.PutUint32(chunk, 9)
+	copy(chunk[4:], "pHYs")
+	binary.BigEndian.PutUint32(chunk[8:], ppm)
+	binary.BigEndian.PutUint32(chunk[12:], ppm)
+	chunk[16] = 1 // meters
+	binary.BigEndian.PutUint32(chunk[17:], crc32.ChecksumIEEE(chunk[4:17]))
+
+	// The pHYs chunk goes after the 8-byte signature
+	// and the 25-byte IHDR chunk.
+	const ihdrEnd = 8 + 25
+	out := make([]byte, 0, len(data)+len(chunk))
+	out = append(out, data[:ihdrEnd]...)
+	out = append(out, chunk...)
+	return append(out, data[ihdrEnd:]...)
+}
+
+// SVG returns an SVG image of b's code with the Swiss cross,
+// 46 × 46 mm, without a quiet zone, as for PNG.
+func (b *SwissBill) SVG() ([]byte, error) {
+	c, err := b.Encode()
+	if err != nil {
+		return nil, err
+	}
+	n := c.Size
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"46mm\" height=\"46mm\" viewBox=\"0 0 %d %d\">\n", n, n)
+	fmt.Fprintf(&buf, "<rect width=\"%d\" height=\"%d\" fill=\"white\"/>\n", n, n)
+	buf.WriteString("<path shape-rendering=\"crispEdges\" fill=\"black\" d=\"")
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; {
+			if !c.Black(x, y) {
+				x++
+				continue
+			}
+			x0 := x
+			for x < n && c.Black(x, y) {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h%dz", x0, y, x-x0, x0-x)
+		}
+	}
+	buf.WriteString("\"/>\n")
+
+	// Draw the cross in module units.
+	k := float64(n) / swissSize
+	num := func(v float64) string {
+		return strconv.FormatFloat(math.Round(v*k*1e4)/1e4, 'f', -1, 64)
+	}
+	for _, r := range swissCross() {
+		fill := "white"
+		if r.black {
+			fill = "black"
+		}
+		fmt.Fprintf(&buf, "<rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" fill=\"%s\"/>\n",
+			num(r.x), num(r.y), num(r.w), num(r.h), fill)
+	}
+	buf.WriteString("</svg>\n")
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"rsc.io/qr"
+)
+
+// A UPI describes a payment request for UPI,
+// India's Unified Payments Interface, as a URI:
+//
+//	upi://pay?pa=shop@bank&pn=Shop&am=10.00&cu=INR
+type UPI struct {
+	Address  string // payee virtual payment address, such as "shop@bank"
+	Name     string // payee name
+	Amount   string // amount in rupees, such as "10.00"; empty lets the payer choose
+	Note     string // optional transaction note
+	Ref      string // optional transaction reference, such as an order number
+	Category string // optional merchant category code
+}
+
+// Check reports whether u describes a valid payment request.
+func (u *UPI) Check() error {
+	user, handle, ok := strings.Cut(u.Address, "@")
+	if !ok || user == "" || handle == "" || strings.Contains(handle, "@") || !isUPIAddress(user) || !isUPIAddress(handle) {
+		return fmt.Errorf("upi: malformed payment address %q", u.Address)
+	}
+	if u.Name == "" {
+		return errors.New("upi: missing payee name")
+	}
+	if u.Amount != "" {
+		if err := checkAmount(u.Amount); err != nil {
+			return fmt.Errorf("upi: %v", err)
+		}
+	}
+	if u.Category != "" && (len(u.Category) != 4 || !isDigits(u.Category)) {
+		return fmt.Errorf("upi: merchant category %q is not four digits", u.Category)
+	}
+	return nil
+}
+
+// isUPIAddress reports whether s uses only the characters
+// allowed in either half of a virtual payment address.
+func isUPIAddress(s string) bool {
+	for _, c := range s {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '.' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the upi: URI for u.
+// It does not check that u is valid; see Check.
+func (u *UPI) String() string {
+	var b strings.Builder
+	b.WriteString("upi://pay")
+	sep := "?"
+	param := func(key, val string) {
+		if val != "" {
+			// UPI apps expect %20, not +, for spaces.
+			b.WriteString(sep + key + "=" + strings.ReplaceAll(url.QueryEscape(val), "+", "%20"))
+			sep = "&"
+		}
+	}
+	param("pa", u.Address)
+	param("pn", u.Name)
+	param("mc", u.Category)
+	param("tr", u.Ref)
+	param("tn", u.Note)
+	param("am", u.Amount)
+	param("cu", "INR")
+	return b.String()
+}
+
+// Encode returns u encoded as a QR code at level l.
+func (u *UPI) Encode(l qr.Level) (*qr.Code, error) {
+	if err := u.Check(); err != nil {
+		return nil, err
+	}
+	return qr.Encode(u.String(), l)
+}
+
+// ParseUPI parses a upi://pay URI, as returned by String.
+func ParseUPI(text string) (*UPI, error) {
+	uri, err := url.Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("upi: %v", err)
+	}
+	if !strings.EqualFold(uri.Scheme, "upi") || !strings.EqualFold(uri.Host, "pay") {
+		return nil, errors.New("upi: not a upi://pay URI")
+	}
+	q, err := url.ParseQuery(uri.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("upi: %v", err)
+	}
+	if cu := q.Get("cu"); cu != "" && cu != "INR" {
+		return nil, fmt.Errorf("upi: unsupported currency %q", cu)
+	}
+	u := &UPI{
+		Address:  q.Get("pa"),
+		Name:     q.Get("pn"),
+		Amount:   q.Get("am"),
+		Note:     q.Get("tn"),
+		Ref:      q.Get("tr"),
+		Category: q.Get("mc"),
+	}
+	if err := u.Check(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
@@ -0,0 +1,189 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"rsc.io/qr"
+)
+
+// A WiFiAuth is a Wi-Fi authentication type.
+type WiFiAuth string
+
+const (
+	WEP     WiFiAuth = "WEP"
+	WPA     WiFiAuth = "WPA" // WPA, WPA2, or WPA3 with a password
+	WPA2EAP WiFiAuth = "WPA2-EAP"
+	NoPass  WiFiAuth = "nopass"
+)
+
+// A WiFi describes a Wi-Fi network, in the format
+//
+//	WIFI:T:WPA;S:network;P:password;;
+//
+// understood by the camera apps on Android and iOS.
+type WiFi struct {
+	SSID     string
+	Password string
+
+	// Auth is the authentication type.
+	// The empty string means WPA if there is a password
+	// and NoPass otherwise.
+	Auth WiFiAuth
+
+	Hidden bool // network does not broadcast its SSID
+
+	// Settings for WPA2-EAP networks.
+	EAPMethod         string // EAP method, such as PEAP, TTLS, or PWD
+	Phase2            string // phase 2 method, such as MSCHAPV2
+	Identity          string
+	AnonymousIdentity string
+}
+
+// wifiSpecial lists the bytes that must be escaped in WIFI: fields.
+const wifiSpecial = `\;,:"`
+
+func (w *WiFi) auth() WiFiAuth {
+	if w.Auth == "" {
+		if w.Password == "" {
+			return NoPass
+		}
+		return WPA
+	}
+	return w.Auth
+}
+
+// Check reports whether w describes a valid network.
+func (w *WiFi) Check() error {
+	if w.SSID == "" {
+		return errors.New("wifi: missing SSID")
+	}
+	if len(w.SSID) > 32 {
+		return fmt.Errorf("wifi: SSID longer than 32 bytes")
+	}
+	auth := w.auth()
+	switch auth {
+	case WEP, WPA, WPA2EAP:
+		if w.Password == "" {
+			return fmt.Errorf("wifi: %s network needs a password", auth)
+		}
+	case NoPass:
+		if w.Password != "" {
+			return errors.New("wifi: open network cannot have a password")
+		}
+	default:
+		return fmt.Errorf("wifi: unknown authentication type %q", auth)
+	}
+	if auth != WPA2EAP && (w.EAPMethod != "" || w.Phase2 != "" || w.Identity != "" || w.AnonymousIdentity != "") {
+		return fmt.Errorf("wifi: EAP settings need authentication type %s", WPA2EAP)
+	}
+	return nil
+}
+
+// String returns the WIFI: text for w.
+// It does not check that w is valid; see Check.
+func (w *WiFi) String() string {
+	var b strings.Builder
+	field := func(key, val string) {
+		if val != "" {
+			b.WriteString(key + ":" + escape(val, wifiSpecial) + ";")
+		}
+	}
+	b.WriteString("WIFI:")
+	field("T", string(w.auth()))
+	if w.SSID != "" {
+		b.WriteString("S:" + quoteHex(escape(w.SSID, wifiSpecial)) + ";")
+	}
+	if w.auth() == WPA2EAP {
+		field("E", w.EAPMethod)
+		field("PH2", w.Phase2)
+		field("A", w.AnonymousIdentity)
+		field("I", w.Identity)
+	}
+	if w.Password != "" {
+		b.WriteString("P:" + quoteHex(escape(w.Password, wifiSpecial)) + ";")
+	}
+	if w.Hidden {
+		field("H", "true")
+	}
+	b.WriteString(";")
+	return b.String()
+}
+
+// quoteHex returns s in double quotes if it consists only of hex
+// digits, so that readers do not mistake it for a hex-encoded value.
+// Since double quotes are escaped within values,
+// only these added quotes are unescaped.
+func quoteHex(s string) string {
+	if !isHex(s) {
+		return s
+	}
+	return `"` + s + `"`
+}
+
+func isHex(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789abcdefABCDEF") == ""
+}
+
+// Encode returns w encoded as a QR code at level l.
+func (w *WiFi) Encode(l qr.Level) (*qr.Code, error) {
+	if err := w.Check(); err != nil {
+		return nil, err
+	}
+	return qr.Encode(w.String(), l)
+}
+
+// ParseWiFi parses WIFI: text, as returned by String.
+func ParseWiFi(text string) (*WiFi, error) {
+	if !strings.HasPrefix(text, "WIFI:") {
+		return nil, errors.New("wifi: missing WIFI: prefix")
+	}
+	rest := text[len("WIFI:"):]
+	w := new(WiFi)
+	for _, f := range split(rest, ';') {
+		if f == "" {
+			continue
+		}
+		key, val, ok := cut(f, ':')
+		if !ok {
+			return nil, fmt.Errorf("wifi: malformed field %q", f)
+		}
+		// Unescaped quotes surround hex-like values.
+		if len(val) > 2 && val[0] == '"' && val[len(val)-1] == '"' && isHex(val[1:len(val)-1]) {
+			val = val[1 : len(val)-1]
+		}
+		val = unescape(val)
+		switch strings.ToUpper(key) {
+		case "T":
+			w.Auth = WiFiAuth(val)
+			for _, a := range []WiFiAuth{WEP, WPA, WPA2EAP, NoPass} {
+				if strings.EqualFold(val, string(a)) {
+					w.Auth = a
+				}
+			}
+		case "S":
+			w.SSID = val
+		case "P":
+			w.Password = val
+		case "H":
+			w.Hidden = strings.EqualFold(val, "true")
+		case "E":
+			w.EAPMethod = val
+		case "PH2":
+			w.Phase2 = val
+		case "I":
+			w.Identity = val
+		case "A":
+			w.AnonymousIdentity = val
+		}
+	}
+	if err := w.Check(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"bytes"
+	"image/png"
+	"reflect"
+	"testing"
+
+	"rsc.io/qr"
+	"rsc.io/qr/decode"
+)
+
+var wifiTests = []struct {
+	w    WiFi
+	text string
+}{
+	{WiFi{SSID: "home", Password: "secret"}, `WIFI:T:WPA;S:home;P:secret;;`},
+	{WiFi{SSID: "guest", Auth: NoPass}, `WIFI:T:nopass;S:guest;;`},
+	{WiFi{SSID: "old", Password: "12345", Auth: WEP, Hidden: true}, `WIFI:T:WEP;S:old;P:"12345";H:true;;`},
+	{WiFi{SSID: `a;b,c:d\e"f`, Password: `"quoted"`}, `WIFI:T:WPA;S:a\;b\,c\:d\\e\"f;P:\"quoted\";;`},
+	{WiFi{SSID: "deadBEEF", Password: "abc"}, `WIFI:T:WPA;S:"deadBEEF";P:"abc";;`},
+	{
+		WiFi{SSID: "corp", Password: "pw", Auth: WPA2EAP, EAPMethod: "PEAP", Phase2: "MSCHAPV2", Identity: "me@corp", AnonymousIdentity: "anon"},
+		`WIFI:T:WPA2-EAP;S:corp;E:PEAP;PH2:MSCHAPV2;A:anon;I:me@corp;P:pw;;`,
+	},
+}
+
+func TestWiFi(t *testing.T) {
+	for _, tt := range wifiTests {
+		if err := tt.w.Check(); err != nil {
+			t.Errorf("%+v: Check: %v", tt.w, err)
+		}
+		if s := tt.w.String(); s != tt.text {
+			t.Errorf("%+v: String() = %s, want %s", tt.w, s, tt.text)
+		}
+		w, err := ParseWiFi(tt.text)
+		if err != nil {
+			t.Errorf("ParseWiFi(%s): %v", tt.text, err)
+			continue
+		}
+		want := tt.w
+		want.Auth = want.auth()
+		if !reflect.DeepEqual(*w, want) {
+			t.Errorf("ParseWiFi(%s) = %+v, want %+v", tt.text, *w, want)
+		}
+	}
+}
+
+func TestParseWiFi(t *testing.T) {
+	// Fields may come in any order, with any case of key and auth type.
+	w, err := ParseWiFi(`WIFI:p:pass;s:net;h:TRUE;t:wpa;;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := WiFi{SSID: "net", Password: "pass", Auth: WPA, Hidden: true}
+	if *w != want {
+		t.Errorf("ParseWiFi = %+v, want %+v", *w, want)
+	}
+
+	for _, text := range []string{
+		`T:WPA;S:x;P:y;;`,
+		`WIFI:T:WPA;S:x;P;;`,
+		`WIFI:T:WPA;P:y;;`,
+		`WIFI:T:nopass;S:x;P:y;;`,
+		`WIFI:T:SAE;S:x;P:y;;`,
+	} {
+		if w, err := ParseWiFi(text); err == nil {
+			t.Errorf("ParseWiFi(%s) = %+v, want error", text, *w)
+		}
+	}
+}
+
+func TestWiFiCheck(t *testing.T) {
+	for _, w := range []WiFi{
+		{},
+		{SSID: "this network name is much too long"},
+		{SSID: "x", Auth: WPA},
+		{SSID: "x", Auth: WEP},
+		{SSID: "x", Auth: NoPass, Password: "y"},
+		{SSID: "x", Auth: "WPA3"},
+		{SSID: "x", Password: "y", Identity: "me"},
+	} {
+		if err := w.Check(); err == nil {
+			t.Errorf("%+v: Check succeeded, want error", w)
+		}
+		if _, err := w.Encode(qr.M); err == nil {
+			t.Errorf("%+v: Encode succeeded, want error", w)
+		}
+	}
+}
+
+func TestWiFiEncode(t *testing.T) {
+	w := WiFi{SSID: `my;net`, Password: "0123", Hidden: true}
+	c, err := w.Encode(qr.Q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := png.Decode(bytes.NewReader(c.PNG()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := decode.Decode(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseWiFi(r.Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Auth = WPA
+	if *got != w {
+		t.Errorf("decoded %+v, want %+v", *got, w)
+	}
+}
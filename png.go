@@ -20,8 +20,18 @@ import (
 // but it runs about 20x faster than calling png.Encode
 // on c.Image().
 func (c *Code) PNG() []byte {
+	return c.PNGBorder(4)
+}
+
+// PNGBorder is like PNG but surrounds the code with
+// a white border of the given number of QR pixels
+// instead of the usual 4. A negative border is treated as zero.
+func (c *Code) PNGBorder(border int) []byte {
+	if border < 0 {
+		border = 0
+	}
 	var p pngWriter
-	return p.encode(c)
+	return p.encode(c, border)
 }
 
 type pngWriter struct {
@@ -34,7 +44,7 @@ type pngWriter struct {
 
 var pngHeader = []byte("\x89PNG\r\n\x1a\n")
 
-func (w *pngWriter) encode(c *Code) []byte {
+func (w *pngWriter) encode(c *Code, border int) []byte {
 	scale := c.Scale
 	siz := c.Size
 
@@ -44,8 +54,8 @@ func (w *pngWriter) encode(c *Code) []byte {
 	w.buf.Write(pngHeader)
 
 	// Header block
-	binary.BigEndian.PutUint32(w.tmp[0:4], uint32((siz+8)*scale))
-	binary.BigEndian.PutUint32(w.tmp[4:8], uint32((siz+8)*scale))
+	binary.BigEndian.PutUint32(w.tmp[0:4], uint32((siz+2*border)*scale))
+	binary.BigEndian.PutUint32(w.tmp[4:8], uint32((siz+2*border)*scale))
 	w.tmp[8] = 1 // 1-bit
 	w.tmp[9] = 0 // gray
 	w.tmp[10] = 0
@@ -57,7 +67,7 @@ func (w *pngWriter) encode(c *Code) []byte {
 	w.writeChunk("tEXt", comment)
 
 	// Data
-	w.zlib.writeCode(c)
+	w.zlib.writeCode(c, border)
 	w.writeChunk("IDAT", w.zlib.bytes.Bytes())
 
 	// End
@@ -85,7 +95,7 @@ func (w *pngWriter) writeChunk(name string, data []byte) {
 	w.buf.Write(w.wctmp[0:4])
 }
 
-func (b *bitWriter) writeCode(c *Code) {
+func (b *bitWriter) writeCode(c *Code, border int) {
 	const ftNone = 0
 
 	b.adler32.Reset()
@@ -105,19 +115,8 @@ func (b *bitWriter) writeCode(c *Code) {
 	b.writeBits(1, 1, false) // final block
 	b.writeBits(1, 2, false) // compressed, fixed Huffman tables
 
-	// White border.
-	// First row.
-	b.byte(ftNone)
-	n := (scale*(siz+8) + 7) / 8
-	b.byte(255)
-	b.repeat(n-1, 1)
-	// 4*scale rows total.
-	b.repeat((4*scale-1)*(1+n), 1+n)
-
-	for i := 0; i < 4*scale; i++ {
-		b.adler32.WriteNByte(ftNone, 1)
-		b.adler32.WriteNByte(255, n)
-	}
+	n := (scale*(siz+2*border) + 7) / 8
+	b.whiteRows(border*scale, n)
 
 	row := make([]byte, 1+n)
 	for y := 0; y < siz; y++ {
@@ -125,7 +124,7 @@ func (b *bitWriter) writeCode(c *Code) {
 		j := 1
 		var z uint8
 		nz := 0
-		for x := -4; x < siz+4; x++ {
+		for x := -border; x < siz+border; x++ {
 			// Raw data.
 			for i := 0; i < scale; i++ {
 				z <<= 1
@@ -139,31 +138,23 @@ func (b *bitWriter) writeCode(c *Code) {
 				}
 			}
 		}
-		if j < len(row) {
-			row[j] = z
+		if nz > 0 {
+			// Left-align the last, partial byte.
+			row[j] = z << (8 - nz)
 		}
 		for _, z := range row {
 			b.byte(z)
 		}
 
 		// Scale-1 copies.
-		b.repeat((scale-1)*(1+n), 1+n)
+		if scale > 1 {
+			b.repeat((scale-1)*(1+n), 1+n)
+		}
 
 		b.adler32.WriteN(row, scale)
 	}
 
-	// White border.
-	// First row.
-	b.byte(ftNone)
-	b.byte(255)
-	b.repeat(n-1, 1)
-	// 4*scale rows total.
-	b.repeat((4*scale-1)*(1+n), 1+n)
-
-	for i := 0; i < 4*scale; i++ {
-		b.adler32.WriteNByte(ftNone, 1)
-		b.adler32.WriteNByte(255, n)
-	}
+	b.whiteRows(border*scale, n)
 
 	// End of block.
 	b.hcode(256)
@@ -174,6 +165,33 @@ func (b *bitWriter) writeCode(c *Code) {
 	b.bytes.Write(b.tmp[0:4])
 }
 
+// whiteRows writes rows white rows of n bytes each,
+// as for the border above and below the code.
+func (b *bitWriter) whiteRows(rows, n int) {
+	if rows == 0 {
+		return
+	}
+	// First row.
+	b.byte(0) // ftNone
+	b.byte(255)
+	if n-1 >= 3 {
+		b.repeat(n-1, 1)
+	} else {
+		for i := 1; i < n; i++ {
+			b.byte(255)
+		}
+	}
+	// Copies of the first row.
+	if rows > 1 {
+		b.repeat((rows-1)*(1+n), 1+n)
+	}
+
+	for i := 0; i < rows; i++ {
+		b.adler32.WriteNByte(0, 1)
+		b.adler32.WriteNByte(255, n)
+	}
+}
+
 // A bitWriter is a write buffer for bit-oriented data like deflate.
 type bitWriter struct {
 	bytes bytes.Buffer
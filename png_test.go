@@ -47,6 +47,41 @@ func TestPNG(t *testing.T) {
 	}
 }
 
+func TestPNGBorder(t *testing.T) {
+	c, err := Encode("hello, world", L)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, scale := range []int{1, 3, 8} {
+		for _, border := range []int{0, 1, 4, 10} {
+			c.Scale = scale
+			m, err := png.Decode(bytes.NewReader(c.PNGBorder(border)))
+			if err != nil {
+				t.Fatalf("scale %d border %d: %v", scale, border, err)
+			}
+			d := scale * (c.Size + 2*border)
+			if r := m.Bounds(); r.Dx() != d || r.Dy() != d {
+				t.Fatalf("scale %d border %d: image is %v, want %d×%d", scale, border, r, d, d)
+			}
+			nbad := 0
+			for y := 0; y < d; y++ {
+				for x := 0; x < d; x++ {
+					v := byte(255)
+					if c.Black(x/scale-border, y/scale-border) {
+						v = 0
+					}
+					if gv := m.At(x, y).(color.Gray).Y; gv != v {
+						t.Errorf("scale %d border %d: %d,%d = %d, want %d", scale, border, x, y, gv, v)
+						if nbad++; nbad >= 20 {
+							t.Fatalf("too many bad pixels")
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
 func BenchmarkPNG(b *testing.B) {
 	c, err := Encode("0123456789012345678901234567890123456789", L)
 	if err != nil {
@@ -0,0 +1,153 @@
+// Copyright 2011 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qr
+
+// Label and receipt printer output for QR codes.
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// PrintOptions control the placement of a code on a printer.
+// Printer stock is assumed to be white, so the quiet zone
+// around the code is left blank rather than printed.
+type PrintOptions struct {
+	Dots int // printer dots per QR pixel; 0 means use the code's Scale
+	X    int // horizontal offset of the code's top left pixel, in dots
+	Y    int // vertical offset of the code's top left pixel, in dots
+}
+
+func (o *PrintOptions) dots(c *Code) int {
+	if o != nil && o.Dots > 0 {
+		return o.Dots
+	}
+	if c.Scale > 0 {
+		return c.Scale
+	}
+	return 1
+}
+
+func (o *PrintOptions) offset() (x, y int) {
+	if o == nil {
+		return 0, 0
+	}
+	return o.X, o.Y
+}
+
+// raster returns the code drawn at dots printer dots per pixel
+// as a 1-bit raster with rowBytes bytes per row, 1 meaning black.
+// Each row starts with pad blank dots.
+func (c *Code) raster(dots, pad int) (rowBytes, rows int, data []byte) {
+	width := pad + c.Size*dots
+	rowBytes = (width + 7) / 8
+	rows = c.Size * dots
+	data = make([]byte, rowBytes*rows)
+	for y := 0; y < c.Size; y++ {
+		row := data[y*dots*rowBytes : (y*dots+1)*rowBytes]
+		for x := 0; x < c.Size; x++ {
+			if !c.Black(x, y) {
+				continue
+			}
+			for i := pad + x*dots; i < pad+(x+1)*dots; i++ {
+				row[i/8] |= 1 << uint(7-i&7)
+			}
+		}
+		for i := 1; i < dots; i++ {
+			copy(data[(y*dots+i)*rowBytes:], row)
+		}
+	}
+	return
+}
+
+// ZPL returns a Zebra ZPL II label printing the code
+// as a ^GF graphic field.
+func (c *Code) ZPL(opt *PrintOptions) []byte {
+	x, y := opt.offset()
+	rowBytes, _, data := c.raster(opt.dots(c), 0)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "^XA\n^FO%d,%d^GFA,%d,%d,%d,", x, y, len(data), len(data), rowBytes)
+	b.WriteString(strings.ToUpper(hex.EncodeToString(data)))
+	b.WriteString("^FS\n^XZ\n")
+	return b.Bytes()
+}
+
+// EncodeZPL returns a Zebra ZPL II label printing text at the
+// given error correction level.
+// When the printer can draw the code itself, EncodeZPL uses the
+// native ^BQ bar code command, which is far smaller than a bitmap.
+// That requires a magnification of 1 to 10 dots per pixel and text
+// free of ZPL control characters; otherwise EncodeZPL falls back to
+// encoding the code with Encode and printing it with Code.ZPL.
+func EncodeZPL(text string, level Level, opt *PrintOptions) ([]byte, error) {
+	dots := 0
+	if opt != nil {
+		dots = opt.Dots
+	}
+	if 1 <= dots && dots <= 10 && L <= level && level <= H && zplSafe(text) {
+		x, y := opt.offset()
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "^XA\n^FO%d,%d^BQN,2,%d^FD%cA,%s^FS\n^XZ\n", x, y, dots, "LMQH"[level], text)
+		return b.Bytes(), nil
+	}
+	c, err := Encode(text, level)
+	if err != nil {
+		return nil, err
+	}
+	return c.ZPL(opt), nil
+}
+
+// zplSafe reports whether s can appear verbatim in a ZPL ^FD field.
+func zplSafe(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == '^' || c == '~' || c < ' ' || c >= 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// EPL returns an Eltron EPL2 label printing the code
+// with a GW direct graphic command.
+func (c *Code) EPL(opt *PrintOptions) []byte {
+	x, y := opt.offset()
+	rowBytes, rows, data := c.raster(opt.dots(c), 0)
+	// EPL prints 0 bits black.
+	for i := range data {
+		data[i] = ^data[i]
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "\nN\nGW%d,%d,%d,%d,", x, y, rowBytes, rows)
+	b.Write(data)
+	b.WriteString("\nP1\n")
+	return b.Bytes()
+}
+
+// ESCPOS returns ESC/POS commands printing the code
+// as a GS v 0 raster bit image.
+// ESC/POS has no absolute positioning, so the offsets are
+// applied by padding the image with blank dots on the left
+// and blank lines on top.
+func (c *Code) ESCPOS(opt *PrintOptions) []byte {
+	x, y := opt.offset()
+	if x < 0 {
+		x = 0
+	}
+	if y < 0 {
+		y = 0
+	}
+	rowBytes, rows, data := c.raster(opt.dots(c), x)
+	rows += y
+	var b bytes.Buffer
+	b.Write([]byte{0x1d, 'v', '0', 0, byte(rowBytes), byte(rowBytes >> 8), byte(rows), byte(rows >> 8)})
+	b.Write(make([]byte, y*rowBytes))
+	b.Write(data)
+	return b.Bytes()
+}
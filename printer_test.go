@@ -0,0 +1,121 @@
+// Copyright 2011 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qr
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// checkRaster checks that data, a 1-bit raster with rowBytes bytes
+// per row, draws c at dots per pixel offset by (dx, dy) dots.
+// If black is 0, 0 bits are black.
+func checkRaster(t *testing.T, c *Code, data []byte, rowBytes, dots, dx, dy int, black byte) {
+	nbad := 0
+	for y := 0; y < len(data)/rowBytes; y++ {
+		for x := 0; x < rowBytes*8; x++ {
+			bit := data[y*rowBytes+x/8] >> uint(7-x&7) & 1
+			want := 1 - black
+			if x >= dx && y >= dy && c.Black((x-dx)/dots, (y-dy)/dots) {
+				want = black
+			}
+			if bit != want {
+				t.Errorf("dot %d,%d = %d, want %d", x, y, bit, want)
+				if nbad++; nbad >= 20 {
+					t.Fatalf("too many bad dots")
+				}
+			}
+		}
+	}
+}
+
+func TestZPL(t *testing.T) {
+	c, err := Encode("hello, world", L)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(c.ZPL(&PrintOptions{Dots: 3, X: 10, Y: 20}))
+	rowBytes := (c.Size*3 + 7) / 8
+	n := rowBytes * c.Size * 3
+	prefix := fmt.Sprintf("^XA\n^FO10,20^GFA,%d,%d,%d,", n, n, rowBytes)
+	if !strings.HasPrefix(out, prefix) || !strings.HasSuffix(out, "^FS\n^XZ\n") {
+		t.Fatalf("ZPL = %q, want %q...^FS^XZ", out, prefix)
+	}
+	data, err := hex.DecodeString(strings.TrimSuffix(out[len(prefix):], "^FS\n^XZ\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != n {
+		t.Fatalf("^GF has %d bytes, want %d", len(data), n)
+	}
+	checkRaster(t, c, data, rowBytes, 3, 0, 0, 1)
+}
+
+func TestEncodeZPL(t *testing.T) {
+	out, err := EncodeZPL("hello, world", Q, &PrintOptions{Dots: 4, X: 1, Y: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "^XA\n^FO1,2^BQN,2,4^FDQA,hello, world^FS\n^XZ\n"; string(out) != want {
+		t.Errorf("EncodeZPL = %q, want %q", out, want)
+	}
+
+	// Too large for ^BQ, or unsafe text: fall back to ^GF.
+	for _, tt := range []struct {
+		text string
+		dots int
+	}{
+		{"hello, world", 12},
+		{"hello^world", 4},
+	} {
+		out, err := EncodeZPL(tt.text, L, &PrintOptions{Dots: tt.dots})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(out, []byte("^GFA,")) {
+			t.Errorf("EncodeZPL(%q, dots=%d) = %q, want ^GF", tt.text, tt.dots, out)
+		}
+	}
+}
+
+func TestEPL(t *testing.T) {
+	c, err := Encode("hello, world", M)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := c.EPL(&PrintOptions{Dots: 2, X: 5, Y: 7})
+	rowBytes := (c.Size*2 + 7) / 8
+	prefix := fmt.Sprintf("\nN\nGW5,7,%d,%d,", rowBytes, c.Size*2)
+	if !bytes.HasPrefix(out, []byte(prefix)) || !bytes.HasSuffix(out, []byte("\nP1\n")) {
+		t.Fatalf("EPL = %q, want %q...P1", out, prefix)
+	}
+	data := out[len(prefix) : len(out)-len("\nP1\n")]
+	if len(data) != rowBytes*c.Size*2 {
+		t.Fatalf("GW has %d bytes, want %d", len(data), rowBytes*c.Size*2)
+	}
+	checkRaster(t, c, data, rowBytes, 2, 0, 0, 0)
+}
+
+func TestESCPOS(t *testing.T) {
+	c, err := Encode("hello, world", H)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := c.ESCPOS(&PrintOptions{Dots: 3, X: 11, Y: 4})
+	rowBytes := (11 + c.Size*3 + 7) / 8
+	rows := 4 + c.Size*3
+	hdr := []byte{0x1d, 'v', '0', 0, byte(rowBytes), byte(rowBytes >> 8), byte(rows), byte(rows >> 8)}
+	if !bytes.HasPrefix(out, hdr) {
+		t.Fatalf("ESCPOS header = %x, want %x", out[:len(hdr)], hdr)
+	}
+	data := out[len(hdr):]
+	if len(data) != rowBytes*rows {
+		t.Fatalf("GS v 0 has %d bytes, want %d", len(data), rowBytes*rows)
+	}
+	checkRaster(t, c, data, rowBytes, 3, 11, 4, 1)
+}
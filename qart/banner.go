@@ -0,0 +1,132 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qart
+
+import (
+	"fmt"
+	"image"
+)
+
+// SetText sets the picture to draw to text, written in the font f
+// (Font5x7 if f is nil), scaled up as much as fits in the middle of
+// the code, between the position squares, for m's Version, Size, Dx, and Dy.
+// SetText also sets a weight map giving the text strokes the highest
+// priority and the modules around them the next highest, so that
+// Encode draws as much of the text as it can.
+// After Encode, GlyphMisses reports the stroke modules it could not draw.
+//
+// SetText lays out the text for the current Version, Size, Dx, and Dy,
+// so set those first. The text is part of the picture: changing Size,
+// Dx, or Dy afterward, as Search does, scales or moves the code relative
+// to the text rather than laying the text out again.
+func (m *Image) SetText(text string, f *Font) error {
+	if f == nil {
+		f = Font5x7
+	}
+	m.Clamp()
+	if err := m.check(); err != nil {
+		return err
+	}
+	n := 17 + 4*m.Version
+	dt := n + m.Size
+
+	// Lay out the glyphs in font pixels.
+	var cols []byte
+	for i, r := range text {
+		g, ok := f.Glyphs[r]
+		if !ok {
+			return fmt.Errorf("qart: font has no glyph for %q", r)
+		}
+		if i > 0 {
+			cols = append(cols, make([]byte, f.Spacing)...)
+		}
+		cols = append(cols, g...)
+	}
+	if len(cols) == 0 {
+		return fmt.Errorf("qart: no text")
+	}
+
+	// Scale by k, keeping a margin of one module on each side
+	// and staying clear of the position squares and format bits,
+	// which fill the top and bottom 9 rows at the left edge.
+	k := (n - 2) / len(cols)
+	if kh := (n - 18) / f.Height; kh < k {
+		k = kh
+	}
+	if k < 1 {
+		return fmt.Errorf("qart: text %q too long for version %d", text, m.Version)
+	}
+	ox := m.Dx + (n-k*len(cols))/2
+	oy := m.Dy + (n-k*f.Height)/2
+
+	src := image.NewGray(image.Rect(0, 0, dt, dt))
+	weight := image.NewGray(image.Rect(0, 0, dt, dt))
+	for i := range src.Pix {
+		src.Pix[i] = 255
+		weight.Pix[i] = 64
+	}
+	in := func(x, y int) bool { return 0 <= x && x < dt && 0 <= y && y < dt }
+	m.glyph = m.glyph[:0]
+	for c, col := range cols {
+		for row := 0; row < f.Height; row++ {
+			if col>>uint(row)&1 == 0 {
+				continue
+			}
+			for y := oy + row*k; y < oy+(row+1)*k; y++ {
+				for x := ox + c*k; x < ox+(c+1)*k; x++ {
+					if !in(x, y) {
+						continue
+					}
+					m.glyph = append(m.glyph, image.Pt(x, y))
+					src.Pix[y*src.Stride+x] = 0
+					weight.Pix[y*weight.Stride+x] = 255
+					// Raise the priority of the surrounding white modules.
+					for dy := -1; dy <= 1; dy++ {
+						for dx := -1; dx <= 1; dx++ {
+							if in(x+dx, y+dy) && weight.Pix[(y+dy)*weight.Stride+x+dx] < 192 {
+								weight.Pix[(y+dy)*weight.Stride+x+dx] = 192
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	glyph := m.glyph
+	m.SetImage(src)
+	m.SetWeight(weight)
+	m.glyph = glyph
+	return nil
+}
+
+// GlyphMisses returns the coordinates, in modules, of the text
+// stroke modules set by SetText that m.Code, the result of the
+// last call to Encode, does not draw black. It uses the Size, Dx,
+// and Dy of that call, which need not be the ones SetText used.
+// Strokes outside the code do not count. If all the text was drawn,
+// GlyphMisses returns an empty list.
+func (m *Image) GlyphMisses() []image.Point {
+	if m.Code == nil || m.Source == nil {
+		return nil
+	}
+	// m.glyph holds pixels of Source, which Encode scaled
+	// to a dt×dt target before placing the code at Dx, Dy.
+	src := m.Source.Bounds().Dx()
+	dt := 17 + 4*m.Version + m.Size
+	at := func(v, d int) int { return (2*v+1)*dt/(2*src) - d }
+	var miss []image.Point
+	seen := make(map[image.Point]bool)
+	for _, p := range m.glyph {
+		p = image.Pt(at(p.X, m.Dx), at(p.Y, m.Dy))
+		if p.X < 0 || p.X >= m.Code.Size || p.Y < 0 || p.Y >= m.Code.Size || seen[p] {
+			continue
+		}
+		seen[p] = true
+		if !m.Code.Black(p.X, p.Y) {
+			miss = append(miss, p)
+		}
+	}
+	return miss
+}
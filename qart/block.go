@@ -0,0 +1,170 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qart
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"rsc.io/qr/gf256"
+)
+
+// A BitBlock is a single Reed-Solomon block of a QR code, along with
+// the linear algebra needed to set individual bits of the block
+// (data or check) while keeping it a valid codeword.
+// The rows of M form a basis of the codewords that can still be
+// added to B without disturbing any bit already set by canSet.
+type BitBlock struct {
+	DataBytes  int
+	CheckBytes int
+	B          []byte
+	M          [][]byte
+	Tmp        []byte
+	RS         *gf256.RSEncoder
+	bdata      []byte
+	cdata      []byte
+	err        error // first internal inconsistency, reported by copyOut
+}
+
+func newBlock(nd, nc int, rs *gf256.RSEncoder, dat, cdata []byte) (*BitBlock, error) {
+	b := &BitBlock{
+		DataBytes:  nd,
+		CheckBytes: nc,
+		B:          make([]byte, nd+nc),
+		Tmp:        make([]byte, nc),
+		RS:         rs,
+		bdata:      dat,
+		cdata:      cdata,
+	}
+	copy(b.B, dat)
+	rs.ECC(b.B[:nd], b.B[nd:])
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(b.Tmp, cdata) {
+		return nil, fmt.Errorf("qart: internal error: check bytes do not match data")
+	}
+
+	b.M = make([][]byte, nd*8)
+	for i := range b.M {
+		row := make([]byte, nd+nc)
+		b.M[i] = row
+		for j := range row {
+			row[j] = 0
+		}
+		row[i/8] = 1 << (7 - uint(i%8))
+		rs.ECC(row[:nd], row[nd:])
+	}
+	return b, nil
+}
+
+// check checks that the check bytes of b.B match its data bytes.
+func (b *BitBlock) check() error {
+	b.RS.ECC(b.B[:b.DataBytes], b.Tmp)
+	if !bytes.Equal(b.B[b.DataBytes:], b.Tmp) {
+		return fmt.Errorf("qart: internal error: block is not a valid codeword")
+	}
+	return nil
+}
+
+func (b *BitBlock) reset(bi uint, bval byte) {
+	if (b.B[bi/8]>>(7-bi&7))&1 == bval {
+		// already has desired bit
+		return
+	}
+	// rows that have already been set
+	m := b.M[len(b.M):cap(b.M)]
+	for _, row := range m {
+		if row[bi/8]&(1<<(7-bi&7)) != 0 {
+			// Found it.
+			xor(b.B, row)
+			return
+		}
+	}
+	if b.err == nil {
+		b.err = fmt.Errorf("qart: internal error: reset of unset bit %d", bi)
+	}
+}
+
+func (b *BitBlock) canSet(bi uint, bval byte) bool {
+	found := false
+	m := b.M
+	for j, row := range m {
+		if row[bi/8]&(1<<(7-bi&7)) == 0 {
+			continue
+		}
+		if !found {
+			found = true
+			if j != 0 {
+				m[0], m[j] = m[j], m[0]
+			}
+			continue
+		}
+		xor(row, m[0])
+	}
+	if !found {
+		return false
+	}
+
+	targ := m[0]
+
+	// Subtract from saved-away rows too.
+	for _, row := range m[len(m):cap(m)] {
+		if row[bi/8]&(1<<(7-bi&7)) == 0 {
+			continue
+		}
+		xor(row, targ)
+	}
+
+	// Found a row with bit #bi == 1 and cut that bit from all the others.
+	// Apply to data and remove from m.
+	if (b.B[bi/8]>>(7-bi&7))&1 != bval {
+		xor(b.B, targ)
+	}
+	n := len(m) - 1
+	m[0], m[n] = m[n], m[0]
+	b.M = m[:n]
+
+	for _, row := range b.M {
+		if row[bi/8]&(1<<(7-bi&7)) != 0 {
+			if b.err == nil {
+				b.err = fmt.Errorf("qart: internal error: bit %d did not reduce", bi)
+			}
+			break
+		}
+	}
+
+	return true
+}
+
+// copyOut copies the block back to the data and check bytes
+// it was created from. It reports any inconsistency found
+// since the block was created.
+func (b *BitBlock) copyOut() error {
+	if b.err != nil {
+		return b.err
+	}
+	if err := b.check(); err != nil {
+		return err
+	}
+	copy(b.bdata, b.B[:b.DataBytes])
+	copy(b.cdata, b.B[b.DataBytes:])
+	return nil
+}
+
+// xor sets dst[i] ^= src[i] for each i.
+// It works a word at a time, since canSet spends
+// most of its time here for large versions.
+func xor(dst, src []byte) {
+	src = src[:len(dst)]
+	for len(dst) >= 8 {
+		binary.LittleEndian.PutUint64(dst, binary.LittleEndian.Uint64(dst)^binary.LittleEndian.Uint64(src))
+		dst, src = dst[8:], src[8:]
+	}
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qart
+
+import (
+	"image/color"
+
+	"rsc.io/qr/coding"
+)
+
+// ColorOptions configures the color rendering of a code.
+// Each module keeps its color from the code but takes its hue
+// from the source image. Dark modules are darkened, keeping their
+// saturation, to luminance at most Threshold-Margin. Light modules
+// are blended toward white, which lowers their saturation, to
+// luminance at least Threshold+Margin. The margin keeps the two
+// far enough apart for scanners. Luminance ranges from 0 to 255.
+type ColorOptions struct {
+	Threshold int // 0 means 128
+	Margin    int // 0 means 48; negative means no margin
+}
+
+// limits returns the maximum luminance of dark modules
+// and the minimum luminance of light modules.
+func (o *ColorOptions) limits() (dark, light int) {
+	thr, margin := 128, 48
+	if o != nil {
+		if o.Threshold > 0 {
+			thr = o.Threshold
+		}
+		if o.Margin > 0 {
+			margin = o.Margin
+		} else if o.Margin < 0 {
+			margin = 0
+		}
+	}
+	dark, light = thr-margin, thr+margin
+	if dark < 0 {
+		dark = 0
+	}
+	if light > 255 {
+		light = 255
+	}
+	return dark, light
+}
+
+// luma returns the luminance of c, using the same weights as makeTarg.
+func luma(c color.RGBA) int {
+	return (299*int(c.R) + 587*int(c.G) + 114*int(c.B) + 500) / 1000
+}
+
+// darken returns c scaled toward black to have luminance at most max.
+// Scaling all three components keeps the hue and saturation.
+func darken(c color.RGBA, max int) color.RGBA {
+	y := luma(c)
+	if y <= max {
+		return c
+	}
+	f := func(v uint8) uint8 { return uint8(int(v) * max / y) }
+	c = color.RGBA{f(c.R), f(c.G), f(c.B), 255}
+	for luma(c) > max {
+		c = color.RGBA{sub1(c.R), sub1(c.G), sub1(c.B), 255}
+	}
+	return c
+}
+
+// lighten returns c blended toward white to have luminance at least min.
+func lighten(c color.RGBA, min int) color.RGBA {
+	y := luma(c)
+	if y >= min {
+		return c
+	}
+	f := func(v uint8) uint8 { return uint8(255 - (255-int(v))*(255-min)/(255-y)) }
+	c = color.RGBA{f(c.R), f(c.G), f(c.B), 255}
+	for luma(c) < min {
+		c = color.RGBA{add1(c.R), add1(c.G), add1(c.B), 255}
+	}
+	return c
+}
+
+func sub1(v uint8) uint8 {
+	if v > 0 {
+		v--
+	}
+	return v
+}
+
+func add1(v uint8) uint8 {
+	if v < 255 {
+		v++
+	}
+	return v
+}
+
+// colorize returns the color rendering of the code cc,
+// using the source colors resampled to the target size.
+func (m *Image) colorize(cc *coding.Code) []byte {
+	dark, light := m.ColorOptions.limits()
+	src := resample(m.Source, 17+4*m.Version+m.Size)
+	b := src.Bounds()
+	return pngEncode(makeImage(0, cc.Size, 4, m.scale(), func(x, y int) uint32 {
+		c := color.RGBA{255, 255, 255, 255}
+		if tx, ty := x+m.Dx, y+m.Dy; 0 <= tx && tx < b.Dx() && 0 <= ty && ty < b.Dy() {
+			// Draw the premultiplied source pixel over white.
+			p := src.Pix[ty*src.Stride+4*tx:]
+			w := 255 - p[3]
+			c = color.RGBA{p[0] + w, p[1] + w, p[2] + w, 255}
+		}
+		if cc.Black(x, y) {
+			c = darken(c, dark)
+		} else {
+			c = lighten(c, light)
+		}
+		return uint32(c.R)<<24 | uint32(c.G)<<16 | uint32(c.B)<<8 | 0xff
+	}))
+}
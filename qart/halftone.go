@@ -0,0 +1,110 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qart
+
+import (
+	"image"
+
+	"rsc.io/qr/coding"
+)
+
+// Kinds of halftone pixels.
+const (
+	htFree   = iota // reproduces the source image
+	htCenter        // center of a data module: carries the bit, diffuses error
+	htSolid         // function pattern or quiet zone: fixed, absorbs error
+)
+
+// halftone returns the halftone rendering of the code cc, laid out by p.
+// Function patterns (position, alignment, and timing patterns and
+// format and version information) are drawn as solid modules.
+// In the other modules, only the center (about a third) carries the bit,
+// and the remaining pixels reproduce the full-resolution source,
+// using Floyd-Steinberg error diffusion that treats the centers
+// as fixed pixels.
+func (m *Image) halftone(p *coding.Plan, cc *coding.Code) *image.Gray {
+	scale := m.scale()
+	if scale < 3 {
+		scale = 3
+	}
+	const border = 4
+	// The center is about a third of the module, widened
+	// if needed to leave equal margins, so that it sits exactly
+	// in the middle, where decoders sample.
+	c := (scale + 1) / 3
+	if (scale-c)%2 != 0 {
+		c++
+	}
+	off := (scale - c) / 2
+	d := (cc.Size + 2*border) * scale
+
+	// Full-resolution source, framed like m.Target.
+	src := makeTarg(m.Source, (17+4*m.Version+m.Size)*scale, int(m.AlphaThreshold))
+	gray := func(x, y int) int {
+		x += (m.Dx - border) * scale
+		y += (m.Dy - border) * scale
+		if y < 0 || y >= len(src) || x < 0 || x >= len(src[y]) || src[y][x] < 0 {
+			return 255
+		}
+		return src[y][x]
+	}
+
+	val := make([]int, d*d)    // desired gray value
+	fixed := make([]byte, d*d) // color of htCenter and htSolid pixels
+	kind := make([]byte, d*d)
+	for y := 0; y < d; y++ {
+		for x := 0; x < d; x++ {
+			i := y*d + x
+			mx, my := x/scale-border, y/scale-border
+			if mx < 0 || mx >= cc.Size || my < 0 || my >= cc.Size {
+				fixed[i], kind[i] = 255, htSolid
+				continue
+			}
+			v := byte(255)
+			if cc.Black(mx, my) {
+				v = 0
+			}
+			switch p.Pixel[my][mx].Role() {
+			case coding.Position, coding.Alignment, coding.Timing, coding.Format, coding.PVersion:
+				fixed[i], kind[i] = v, htSolid
+				continue
+			}
+			if sx, sy := x%scale-off, y%scale-off; 0 <= sx && sx < c && 0 <= sy && sy < c {
+				fixed[i], kind[i] = v, htCenter
+			}
+			val[i] = gray(x, y)
+		}
+	}
+
+	out := image.NewGray(image.Rect(0, 0, d, d))
+	add := func(x, y, err int) {
+		if 0 <= x && x < d && y < d {
+			val[y*d+x] += err
+		}
+	}
+	for y := 0; y < d; y++ {
+		for x := 0; x < d; x++ {
+			i := y*d + x
+			v := fixed[i]
+			switch kind[i] {
+			case htSolid:
+				out.Pix[y*out.Stride+x] = v
+				continue
+			case htFree:
+				v = 0
+				if val[i] >= 128 {
+					v = 255
+				}
+			}
+			out.Pix[y*out.Stride+x] = v
+			err := val[i] - int(v)
+			add(x+1, y, err*7/16)
+			add(x-1, y+1, err*3/16)
+			add(x, y+1, err*5/16)
+			add(x+1, y+1, err*1/16)
+		}
+	}
+	return out
+}
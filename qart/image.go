@@ -0,0 +1,91 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qart
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	"rsc.io/qr/resize"
+)
+
+// resample returns a copy of i scaled to fit in a max×max square.
+func resample(i image.Image, max int) *image.RGBA {
+	b := i.Bounds()
+	dx, dy := max, max
+	if b.Dx() > b.Dy() {
+		dy = b.Dy() * dx / b.Dx()
+	} else {
+		dx = b.Dx() * dy / b.Dy()
+	}
+	return resize.Resize(i, i.Bounds(), dx, dy, nil)
+}
+
+// makeTarg returns the grayscale target for the source image m
+// scaled to fit in a max×max square. Pixels with alpha less than
+// alpha are -1 (don't care). Other partially transparent pixels
+// are drawn over white.
+func makeTarg(m image.Image, max, alpha int) [][]int {
+	if alpha < 1 {
+		alpha = 1
+	}
+	i := resample(m, max)
+	b := i.Bounds()
+	dx, dy := b.Dx(), b.Dy()
+	targ := make([][]int, dy)
+	arr := make([]int, dx*dy)
+	for y := 0; y < dy; y++ {
+		targ[y], arr = arr[:dx], arr[dx:]
+		row := targ[y]
+		for x := 0; x < dx; x++ {
+			p := i.Pix[y*i.Stride+4*x:]
+			r, g, b, a := p[0], p[1], p[2], p[3]
+			if int(a) < alpha {
+				row[x] = -1
+			} else {
+				row[x] = int((299*uint32(r)+587*uint32(g)+114*uint32(b)+500)/1000) + 255 - int(a)
+			}
+		}
+	}
+	return targ
+}
+
+// targSize returns the larger dimension of the target t,
+// which is the max passed to makeTarg.
+func targSize(t [][]int) int {
+	n := len(t)
+	if n > 0 && len(t[0]) > n {
+		n = len(t[0])
+	}
+	return n
+}
+
+func pngEncode(c image.Image) []byte {
+	var b bytes.Buffer
+	png.Encode(&b, c)
+	return b.Bytes()
+}
+
+func makeImage(pt, size, border, scale int, f func(x, y int) uint32) *image.RGBA {
+	d := (size + 2*border) * scale
+	c := image.NewRGBA(image.Rect(0, 0, d, d))
+
+	// white
+	u := &image.Uniform{C: color.White}
+	draw.Draw(c, c.Bounds(), u, image.ZP, draw.Src)
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			r := image.Rect((x+border)*scale, (y+border)*scale, (x+border+1)*scale, (y+border+1)*scale)
+			rgba := f(x, y)
+			u.C = color.RGBA{byte(rgba >> 24), byte(rgba >> 16), byte(rgba >> 8), byte(rgba)}
+			draw.Draw(c, r, u, image.ZP, draw.Src)
+		}
+	}
+	return c
+}
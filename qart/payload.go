@@ -0,0 +1,291 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qart
+
+import (
+	"errors"
+	"strings"
+
+	"rsc.io/qr/coding"
+)
+
+// A Payload describes the data in an artistic QR code:
+// fixed Prefix segments, then a free segment whose contents
+// Encode chooses in order to draw the picture, then fixed
+// Suffix segments. The free segment fills all the space
+// not needed by the fixed segments.
+type Payload struct {
+	Prefix []coding.Encoding
+	Free   Free
+	Suffix []coding.Encoding // not allowed with FreePadding
+}
+
+// A Free describes the kind of free segment in a Payload.
+type Free int
+
+const (
+	// FreeNumeric is a numeric segment: decimal digits,
+	// 10 bits for every 3 digits.
+	FreeNumeric Free = iota
+
+	// FreeAlphanumeric is an alphanumeric segment:
+	// 0-9A-Z$%*+-./: and space, 11 bits for every 2 characters.
+	FreeAlphanumeric
+
+	// FreeBytes is an 8-bit segment of arbitrary bytes.
+	FreeBytes
+
+	// FreePadding is not a segment at all: it is the padding
+	// after the QR terminator that ends the data. Readers ignore
+	// the padding, so the fixed data is read back unchanged,
+	// but FreePadding cannot be followed by Suffix segments.
+	FreePadding
+)
+
+// Fragment returns the Payload for url followed by "#" and
+// free digits. This is the traditional qart payload: browsers
+// do not send the fragment to the server, and most pages
+// ignore unknown fragments.
+func Fragment(url string) *Payload {
+	return &Payload{
+		Prefix: []coding.Encoding{coding.String(url + "#")},
+		Free:   FreeNumeric,
+	}
+}
+
+// Query returns the Payload for url with an added query
+// parameter name whose value is free digits. If url has
+// a fragment, the parameter is added before it.
+func Query(url, name string) *Payload {
+	url, frag, hasFrag := strings.Cut(url, "#")
+	sep := "?"
+	if strings.Contains(url, "?") {
+		sep = "&"
+	}
+	p := &Payload{
+		Prefix: []coding.Encoding{coding.String(url + sep + name + "=")},
+		Free:   FreeNumeric,
+	}
+	if hasFrag {
+		p.Suffix = []coding.Encoding{coding.String("#" + frag)}
+	}
+	return p
+}
+
+// Padding returns the Payload for text followed by free padding.
+// Text can be anything at all, such as a Wi-Fi configuration or plain text,
+// since readers stop at the end of the text.
+func Padding(text string) *Payload {
+	return &Payload{
+		Prefix: []coding.Encoding{segment(text)},
+		Free:   FreePadding,
+	}
+}
+
+// Filler returns the Payload for text followed by sep and
+// then free bytes, for readers that ignore everything after
+// a separator such as a NUL byte or newline.
+func Filler(text, sep string) *Payload {
+	return &Payload{
+		Prefix: []coding.Encoding{coding.String(text + sep)},
+		Free:   FreeBytes,
+	}
+}
+
+// segment returns the smallest single-segment encoding of text.
+func segment(text string) coding.Encoding {
+	switch {
+	case coding.Num(text).Check() == nil:
+		return coding.Num(text)
+	case coding.Alpha(text).Check() == nil:
+		return coding.Alpha(text)
+	}
+	return coding.String(text)
+}
+
+const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// group describes how a free segment packs characters into bits:
+// chars characters in each group of bits bits, with a group value
+// required to be less than limit.
+type group struct {
+	bits  int
+	chars int
+	limit int
+}
+
+var groups = []group{
+	FreeNumeric:      {10, 3, 1000},
+	FreeAlphanumeric: {11, 2, 45 * 45},
+	FreeBytes:        {8, 1, 256},
+	FreePadding:      {8, 1, 256},
+}
+
+// hardZero is the bit in each group (counting from the most
+// significant bit) that Encode forces to zero when a group value
+// is out of range. For digits, a group of 10 bits is out of range
+// (at least 1000) only if the top five bits are set, and clearing
+// the fourth of them makes the value at most 959. For alphanumerics,
+// a group of 11 bits is at least 2025 only if the top six bits are
+// set, and clearing the fourth makes the value at most 1919.
+const hardZero = 3
+
+// chars converts the group value v to characters in buf.
+func (f Free) chars(buf []byte, v int) {
+	switch f {
+	case FreeNumeric:
+		buf[0] = byte(v/100 + '0')
+		buf[1] = byte(v/10%10 + '0')
+		buf[2] = byte(v%10 + '0')
+	case FreeAlphanumeric:
+		buf[0] = alphabet[v/45]
+		buf[1] = alphabet[v%45]
+	default:
+		buf[0] = byte(v)
+	}
+}
+
+// A padding is a coding.Encoding for free padding: the zero bits
+// of the terminator and byte alignment, followed by padding bytes.
+type padding struct {
+	zero int
+	data []byte
+}
+
+func (p padding) Check() error              { return nil }
+func (p padding) Bits(v coding.Version) int { return p.zero + 8*len(p.data) }
+func (p padding) Encode(b *coding.Bits, v coding.Version) {
+	b.Write(0, p.zero)
+	for _, c := range p.data {
+		b.Write(uint(c), 8)
+	}
+}
+
+// A layout is a Payload laid out in a specific QR version.
+type layout struct {
+	*Payload
+	v    coding.Version
+	bbit int    // offset of first free bit
+	mbit int    // offset of first fixed bit after the free bits
+	zero int    // zero bits before free padding
+	free []byte // free characters
+}
+
+var errTooLong = errors.New("qart: payload too long for QR version")
+
+// layout lays out the payload in n data bits of a QR code with version v,
+// with the free characters initially all zero.
+func (p *Payload) layout(v coding.Version, n int) (*layout, error) {
+	if p.Free < FreeNumeric || p.Free > FreePadding {
+		return nil, errors.New("qart: invalid free segment")
+	}
+	if p.Free == FreePadding && len(p.Suffix) > 0 {
+		return nil, errors.New("qart: free padding cannot have a suffix")
+	}
+	l := &layout{Payload: p, v: v}
+	var b coding.Bits
+	for _, e := range p.Prefix {
+		if err := e.Check(); err != nil {
+			return nil, err
+		}
+		e.Encode(&b, v)
+	}
+	g := groups[p.Free]
+
+	if p.Free == FreePadding {
+		pb := b.Bits()
+		if pb > n {
+			return nil, errTooLong
+		}
+		// Terminator, then zeros to a byte boundary.
+		l.zero = 4
+		l.zero += -(pb + l.zero) & 7
+		if l.zero > n-pb {
+			l.zero = n - pb
+		}
+		l.bbit = pb + l.zero
+		l.mbit = n
+		l.free = make([]byte, (n-l.bbit)/8)
+		return l, nil
+	}
+
+	empty := l.segment(nil)
+	empty.Encode(&b, v)
+	l.bbit = b.Bits()
+	sbit := 0
+	for _, e := range p.Suffix {
+		if err := e.Check(); err != nil {
+			return nil, err
+		}
+		sbit += e.Bits(v)
+	}
+	avail := n - l.bbit - sbit
+	if avail < 0 {
+		return nil, errTooLong
+	}
+	ngroup := avail / g.bits
+	// The count field limits the number of characters.
+	if max := (1<<uint(empty.Bits(v)-4) - 1) / g.chars; ngroup > max {
+		ngroup = max
+	}
+	l.mbit = l.bbit + ngroup*g.bits
+	l.free = make([]byte, ngroup*g.chars)
+	if p.Free != FreeBytes {
+		for i := range l.free {
+			l.free[i] = '0'
+		}
+	}
+	return l, nil
+}
+
+// segment returns the free segment holding the characters free.
+func (l *layout) segment(free []byte) coding.Encoding {
+	switch l.Free {
+	case FreeNumeric:
+		return coding.Num(free)
+	case FreeAlphanumeric:
+		return coding.Alpha(free)
+	case FreeBytes:
+		return coding.String(free)
+	}
+	return padding{l.zero, free}
+}
+
+// segments returns the complete list of segments
+// with the current free characters.
+func (l *layout) segments() []coding.Encoding {
+	var list []coding.Encoding
+	list = append(list, l.Prefix...)
+	list = append(list, l.segment(l.free))
+	list = append(list, l.Suffix...)
+	return list
+}
+
+// bits returns the data bits for the current segments,
+// including the check bytes.
+func (l *layout) bits(level coding.Level) *coding.Bits {
+	var b coding.Bits
+	for _, e := range l.segments() {
+		e.Encode(&b, l.v)
+	}
+	b.AddCheckBytes(l.v, level)
+	return &b
+}
+
+// String returns the text of the payload with the current free characters.
+func (l *layout) String() string {
+	var s strings.Builder
+	for _, e := range l.segments() {
+		switch e := e.(type) {
+		case coding.Num:
+			s.WriteString(string(e))
+		case coding.Alpha:
+			s.WriteString(string(e))
+		case coding.String:
+			s.WriteString(string(e))
+		}
+	}
+	return s.String()
+}
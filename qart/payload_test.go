@@ -0,0 +1,66 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qart
+
+import (
+	"strings"
+	"testing"
+
+	"rsc.io/qr/coding"
+)
+
+func TestPayload(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		payload *Payload
+		prefix  string
+		suffix  string
+		free    string // characters allowed in the free part
+	}{
+		{"fragment", Fragment("https://example.com/x"), "https://example.com/x#", "", "0123456789"},
+		{"query", Query("https://example.com/x", "q"), "https://example.com/x?q=", "", "0123456789"},
+		{"query&", Query("https://example.com/x?a=1#top", "q"), "https://example.com/x?a=1&q=", "#top", "0123456789"},
+		{"padding", Padding("WIFI:S:home;T:WPA;P:secret;;"), "WIFI:S:home;T:WPA;P:secret;;", "", ""},
+		{"padding num", Padding("0123456789"), "0123456789", "", ""},
+		{"filler", Filler("hello, world", "\n"), "hello, world\n", "", ""},
+		{"alpha", &Payload{
+			Prefix: []coding.Encoding{coding.Alpha("HTTPS://EXAMPLE.COM/")},
+			Free:   FreeAlphanumeric,
+		}, "HTTPS://EXAMPLE.COM/", "", alphabet},
+	} {
+		m := &Image{Payload: tt.payload, Version: 6, Mask: 2, Seed: 1}
+		m.SetImage(disk(100))
+		if _, err := m.Encode(); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !strings.HasPrefix(m.Text, tt.prefix) || !strings.HasSuffix(m.Text, tt.suffix) {
+			t.Errorf("%s: Text = %q, want %q...%q", tt.name, m.Text, tt.prefix, tt.suffix)
+			continue
+		}
+		free := m.Text[len(tt.prefix) : len(m.Text)-len(tt.suffix)]
+		if tt.payload.Free == FreePadding && free != "" {
+			t.Errorf("%s: Text = %q, want %q", tt.name, m.Text, tt.prefix)
+		}
+		if tt.free != "" && strings.Trim(free, tt.free) != "" {
+			t.Errorf("%s: invalid free characters %q", tt.name, free)
+		}
+		if a := agreement(m); a < 0.6 {
+			t.Errorf("%s: code matches %.0f%% of target, want ≥60%%", tt.name, 100*a)
+		}
+	}
+}
+
+func TestPayloadTooLong(t *testing.T) {
+	m := &Image{Payload: Padding(strings.Repeat("x", 200)), Version: 2}
+	m.SetImage(disk(50))
+	if _, err := m.Encode(); err == nil {
+		t.Fatal("Encode of oversized payload succeeded")
+	}
+	m.Payload = &Payload{Prefix: []coding.Encoding{coding.String("x")}, Free: FreePadding, Suffix: []coding.Encoding{coding.String("y")}}
+	if _, err := m.Encode(); err == nil {
+		t.Fatal("Encode of free padding with suffix succeeded")
+	}
+}
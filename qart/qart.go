@@ -0,0 +1,722 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package qart generates artistic QR codes, in which the code's
+// pixels are chosen to resemble a picture while still encoding
+// a chosen URL or text. The algorithms are described at
+// https://research.swtch.com/qart.
+//
+// By default the data is a URL followed by "#" and a run of digits:
+// the digits are free to vary, and together with the error
+// correction bytes they give enough freedom to draw most
+// of the picture. A Payload can put the free data elsewhere,
+// such as in a query parameter or in the padding after the
+// end of arbitrary text.
+package qart // import "rsc.io/qr/qart"
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"math/rand"
+	"sort"
+	"time"
+
+	"rsc.io/qr"
+	"rsc.io/qr/coding"
+	"rsc.io/qr/gf256"
+)
+
+// An Image describes an artistic QR code: the picture to draw,
+// the data to encode, and the QR parameters and placement to use.
+type Image struct {
+	// Source is the picture to draw.
+	// Set it with SetImage, which discards the cached Target.
+	Source image.Image
+
+	// Target is the grayscale target computed from Source:
+	// 0 is black, 255 is white, and -1 means don't care.
+	Target [][]int
+
+	// AlphaThreshold is the alpha value below which Source pixels
+	// are don't care, left for Encode to choose freely.
+	// Zero means only fully transparent pixels are don't care.
+	// More opaque pixels are drawn over white.
+	// SetImage discards the Target computed with an earlier threshold.
+	AlphaThreshold uint8
+
+	// Weight is an optional grayscale map of how much each part
+	// of Source matters, framed the same way as Source:
+	// white pixels matter most and black pixels least.
+	// Encode controls pixels in decreasing order of weight,
+	// and within equal weights, decreasing order of contrast.
+	// SetImage and SetWeight discard the cached weights.
+	Weight  image.Image
+	weights [][]int
+
+	glyph []image.Point // text strokes drawn by SetText, in Source coordinates
+
+	Dx       int      // horizontal offset of the code within Target
+	Dy       int      // vertical offset of the code within Target
+	URL      string   // URL to encode, if Payload is nil
+	Version  int      // QR version, from 1 to 40; 0 means 1
+	Level    qr.Level // QR error correction level
+	Mask     int      // QR mask, from 0 to 7
+	Scale    int      // image pixels per QR pixel in Code; 0 means automatic
+	Rotation int      // number of quarter turns to rotate the code
+	Size     int      // extra target pixels beyond the code size
+
+	// Payload describes the data to encode.
+	// If Payload is nil, Encode uses Fragment(URL).
+	Payload *Payload
+
+	// Rand says to pick the pixels randomly.
+	Rand bool
+
+	// Dither says to dither instead of using threshold pixel layout.
+	Dither bool
+
+	// OnlyDataBits says to use only data bits, not check bits.
+	OnlyDataBits bool
+
+	// Seed seeds the random choices made by Encode:
+	// the tie-breaks between equally important pixels
+	// and, if Rand is set, the pixel priorities themselves.
+	// Encoding the same Image with the same Seed always
+	// produces the same code. If Seed is zero, Encode uses
+	// a seed derived from the current time.
+	// Either way, Encode records the seed it used in UsedSeed.
+	Seed     int64
+	UsedSeed int64
+
+	// Control is a PNG showing the pixels that we controlled.
+	// Pixels we don't control are grayed out.
+	SaveControl bool
+	Control     []byte
+
+	// Halftone is a PNG of the code rendered as a halftone:
+	// only the center third of each data module carries its bit,
+	// and the rest of the module reproduces the source image.
+	// It uses Scale pixels per module, but at least 3.
+	SaveHalftone bool
+	Halftone     []byte
+
+	// Color is a PNG of the code in color: each module keeps
+	// its color from the code, lightened or darkened as described
+	// by ColorOptions, but takes its hue from Source.
+	SaveColor    bool
+	ColorOptions *ColorOptions
+	Color        []byte
+
+	// Report describes, module by module, which modules
+	// were controlled and which match the target.
+	SaveReport bool
+	Report     *Report
+
+	// Code is the final QR code, and Text is the text it encodes,
+	// including the free characters chosen by Encode
+	// (but not free padding, which is not part of the text).
+	Code *qr.Code
+	Text string
+}
+
+// SetImage sets the picture to draw.
+func (m *Image) SetImage(src image.Image) {
+	m.Source = src
+	m.Target = nil
+	m.weights = nil
+	m.glyph = nil
+}
+
+// SetWeight sets the weight map.
+func (m *Image) SetWeight(w image.Image) {
+	m.Weight = w
+	m.weights = nil
+}
+
+// SetFile sets the picture to draw to the image encoded in data,
+// in any format registered with the image package.
+func (m *Image) SetFile(data []byte) error {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	m.SetImage(src)
+	return nil
+}
+
+// Small reports whether the code is small enough to be
+// displayed at full scale in a 512-pixel square.
+func (m *Image) Small() bool {
+	return m.scale()*(17+4*m.Version) < 512
+}
+
+// Clamp fills in defaults: a zero Version means version 1.
+// It does not change invalid values; Encode reports them.
+func (m *Image) Clamp() {
+	if m.Version == 0 {
+		m.Version = coding.MinVersion
+	}
+}
+
+// check reports whether the version, level, and mask are valid.
+func (m *Image) check() error {
+	if m.Version < coding.MinVersion || m.Version > coding.MaxVersion {
+		return fmt.Errorf("qart: invalid version %d", m.Version)
+	}
+	if m.Level < qr.L || m.Level > qr.H {
+		return fmt.Errorf("qart: invalid level %d", m.Level)
+	}
+	if m.Mask < 0 || m.Mask > 7 {
+		return fmt.Errorf("qart: invalid mask %d", m.Mask)
+	}
+	return nil
+}
+
+// scale returns the number of image pixels per QR pixel.
+// If m.Scale is not set, it is 8 for small codes,
+// halved as needed for larger versions to keep the
+// image from growing beyond about 512 pixels.
+func (m *Image) scale() int {
+	if m.Scale > 0 {
+		return m.Scale
+	}
+	scale := 8
+	for scale > 2 && scale*(17+4*m.Version) >= 512 {
+		scale /= 2
+	}
+	return scale
+}
+
+// A Pixinfo records what Encode knows about a single
+// data or check pixel.
+type Pixinfo struct {
+	X        int
+	Y        int
+	Pix      coding.Pixel
+	Targ     byte
+	DTarg    int
+	Contrast int
+	HardZero bool
+	Block    *BitBlock
+	Bit      uint
+}
+
+// A Pixorder gives the priority for setting the bit at offset Off.
+// Encode sets bits in decreasing priority order.
+type Pixorder struct {
+	Off      int
+	Priority int
+}
+
+type byPriority []Pixorder
+
+func (x byPriority) Len() int           { return len(x) }
+func (x byPriority) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x byPriority) Less(i, j int) bool { return x[i].Priority > x[j].Priority }
+
+func (m *Image) target(x, y int) (targ byte, contrast int) {
+	tx := x + m.Dx
+	ty := y + m.Dy
+	if ty < 0 || ty >= len(m.Target) || tx < 0 || tx >= len(m.Target[ty]) {
+		return 255, -1
+	}
+
+	v0 := m.Target[ty][tx]
+	if v0 < 0 {
+		return 255, -1
+	}
+	targ = byte(v0)
+
+	n := 0
+	sum := 0
+	sumsq := 0
+	const del = 5
+	for dy := -del; dy <= del; dy++ {
+		for dx := -del; dx <= del; dx++ {
+			if 0 <= ty+dy && ty+dy < len(m.Target) && 0 <= tx+dx && tx+dx < len(m.Target[ty+dy]) {
+				v := m.Target[ty+dy][tx+dx]
+				if v < 0 {
+					// Don't-care pixels have no contrast to contribute.
+					continue
+				}
+				sum += v
+				sumsq += v * v
+				n++
+			}
+		}
+	}
+
+	avg := sum / n
+	contrast = sumsq/n - avg*avg
+	return targ, m.weigh(x, y, contrast)
+}
+
+// Contrast limits. Pixinfo.Contrast is the local contrast,
+// at most maxContrast, plus the pixel's weight times
+// maxContrast+1. HardZero pixels have contrast hardContrast,
+// above all others.
+const (
+	maxContrast  = 1<<14 - 1
+	hardContrast = 1 << 22
+)
+
+// weigh returns the contrast adjusted for the weight of the code pixel x, y.
+func (m *Image) weigh(x, y, contrast int) int {
+	if contrast > maxContrast {
+		contrast = maxContrast
+	}
+	if m.weights == nil {
+		return contrast
+	}
+	w := 0
+	if ty, tx := y+m.Dy, x+m.Dx; ty < len(m.weights) && tx < len(m.weights[ty]) {
+		w = m.weights[ty][tx]
+	}
+	if w < 0 {
+		w = 0
+	}
+	return w*(maxContrast+1) + contrast
+}
+
+func (m *Image) rotate(p *coding.Plan, rot int) {
+	if rot == 0 {
+		return
+	}
+
+	N := len(p.Pixel)
+	pix := make([][]coding.Pixel, N)
+	apix := make([]coding.Pixel, N*N)
+	for i := range pix {
+		pix[i], apix = apix[:N], apix[N:]
+	}
+
+	switch rot {
+	case 0:
+		// ok
+	case 1:
+		for y := 0; y < N; y++ {
+			for x := 0; x < N; x++ {
+				pix[y][x] = p.Pixel[x][N-1-y]
+			}
+		}
+	case 2:
+		for y := 0; y < N; y++ {
+			for x := 0; x < N; x++ {
+				pix[y][x] = p.Pixel[N-1-y][N-1-x]
+			}
+		}
+	case 3:
+		for y := 0; y < N; y++ {
+			for x := 0; x < N; x++ {
+				pix[y][x] = p.Pixel[N-1-x][y]
+			}
+		}
+	}
+
+	p.Pixel = pix
+}
+
+var errNoSource = errors.New("qart: no source image")
+
+// Encode computes the QR code, saving it in m.Code
+// and, if m.SaveControl is set, saving the control image
+// in m.Control.
+func (m *Image) Encode() (*qr.Code, error) {
+	m.Clamp()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	if m.Source == nil {
+		return nil, errNoSource
+	}
+	dt := 17 + 4*m.Version + m.Size
+	if targSize(m.Target) != dt {
+		m.Target = makeTarg(m.Source, dt, int(m.AlphaThreshold))
+	}
+	if m.Weight == nil {
+		m.weights = nil
+	} else if targSize(m.weights) != dt {
+		m.weights = makeTarg(m.Weight, dt, 0)
+	}
+	p, err := coding.NewPlan(coding.Version(m.Version), coding.Level(m.Level), coding.Mask(m.Mask))
+	if err != nil {
+		return nil, err
+	}
+
+	m.rotate(p, m.Rotation)
+
+	m.UsedSeed = m.Seed
+	if m.UsedSeed == 0 {
+		m.UsedSeed = time.Now().UnixNano()
+	}
+	rand := rand.New(rand.NewSource(m.UsedSeed))
+
+	pl := m.Payload
+	if pl == nil {
+		pl = Fragment(m.URL)
+	}
+	g := groups[pl.Free]
+
+	// QR parameters.
+	nd0 := p.DataBytes / p.Blocks
+	nc := p.CheckBytes / p.Blocks
+	extra := p.DataBytes - nd0*p.Blocks
+	rs := gf256.NewRSEncoder(coding.Field, nc)
+
+	// Build information about pixels, indexed by data/check bit number.
+	pixByOff := make([]Pixinfo, (p.DataBytes+p.CheckBytes)*8)
+	expect := make([][]bool, len(p.Pixel))
+	for y, row := range p.Pixel {
+		expect[y] = make([]bool, len(row))
+		for x, pix := range row {
+			targ, contrast := m.target(x, y)
+			if m.Rand && contrast >= 0 {
+				contrast = m.weigh(x, y, rand.Intn(128)+64*((x+y)%2)+64*((x+y)%3%2))
+			}
+			expect[y][x] = pix&coding.Black != 0
+			if r := pix.Role(); r == coding.Data || r == coding.Check {
+				pixByOff[pix.Offset()] = Pixinfo{X: x, Y: y, Pix: pix, Targ: targ, Contrast: contrast}
+			}
+		}
+	}
+
+	// Choose the random tie-breaks in pixel priority once,
+	// so that retrying after fixing bad digits (goto Again below)
+	// leaves unaffected blocks unchanged and the retries converge.
+	tie := make([]int, len(pixByOff))
+	for i := range tie {
+		tie[i] = rand.Intn(256)
+	}
+
+Again:
+	// Lay out the payload with zero free characters
+	// to compute the template data.
+	l, err := pl.layout(p.Version, p.DataBytes*8)
+	if err != nil {
+		return nil, err
+	}
+	b := l.bits(p.Level)
+	data := b.Bytes()
+	bbit, mbit := l.bbit, l.mbit
+
+	doff := 0 // data offset
+	coff := 0 // checksum offset
+
+	// Choose pixels.
+	bitblocks := make([]*BitBlock, p.Blocks)
+	for blocknum := 0; blocknum < p.Blocks; blocknum++ {
+		// The last extra blocks have one more data byte.
+		nd := nd0
+		if blocknum >= p.Blocks-extra {
+			nd++
+		}
+
+		bdata := data[doff/8 : doff/8+nd]
+		cdata := data[p.DataBytes+coff/8 : p.DataBytes+coff/8+nc]
+		bb, err := newBlock(nd, nc, rs, bdata, cdata)
+		if err != nil {
+			return nil, err
+		}
+		bitblocks[blocknum] = bb
+
+		// Determine which bits in this block we can try to edit.
+		lo, hi := 0, nd*8
+		if lo < bbit-doff {
+			lo = bbit - doff
+			if lo > hi {
+				lo = hi
+			}
+		}
+		if hi > mbit-doff {
+			hi = mbit - doff
+			if hi < lo {
+				hi = lo
+			}
+		}
+
+		// Preserve [0, lo) and [hi, nd*8).
+		for i := 0; i < lo; i++ {
+			if !bb.canSet(uint(i), (bdata[i/8]>>uint(7-i&7))&1) {
+				return nil, fmt.Errorf("cannot preserve required bits")
+			}
+		}
+		for i := hi; i < nd*8; i++ {
+			if !bb.canSet(uint(i), (bdata[i/8]>>uint(7-i&7))&1) {
+				return nil, fmt.Errorf("cannot preserve required bits")
+			}
+		}
+
+		// Can edit [lo, hi) and checksum bits to hit target.
+		// Determine which ones to try first.
+		order := make([]Pixorder, (hi-lo)+nc*8)
+		for i := lo; i < hi; i++ {
+			order[i-lo].Off = doff + i
+		}
+		for i := 0; i < nc*8; i++ {
+			order[hi-lo+i].Off = p.DataBytes*8 + coff + i
+		}
+		if m.OnlyDataBits {
+			order = order[:hi-lo]
+		}
+		for i := range order {
+			po := &order[i]
+			po.Priority = pixByOff[po.Off].Contrast<<8 | tie[po.Off]
+		}
+		sort.Sort(byPriority(order))
+
+		const mark = false
+		for i := range order {
+			po := &order[i]
+			pinfo := &pixByOff[po.Off]
+			bval := pinfo.Targ
+			if bval < 128 {
+				bval = 1
+			} else {
+				bval = 0
+			}
+			pix := pinfo.Pix
+			if pix&coding.Invert != 0 {
+				bval ^= 1
+			}
+			if pinfo.HardZero {
+				bval = 0
+			}
+
+			var bi int
+			if pix.Role() == coding.Data {
+				bi = po.Off - doff
+			} else {
+				bi = po.Off - p.DataBytes*8 - coff + nd*8
+			}
+			if bb.canSet(uint(bi), bval) {
+				pinfo.Block = bb
+				pinfo.Bit = uint(bi)
+				if mark {
+					p.Pixel[pinfo.Y][pinfo.X] = coding.Black
+				}
+			} else {
+				if pinfo.HardZero {
+					return nil, fmt.Errorf("qart: internal error: cannot clear bit %d", bi)
+				}
+				if mark {
+					p.Pixel[pinfo.Y][pinfo.X] = 0
+				}
+			}
+		}
+		if err := bb.copyOut(); err != nil {
+			return nil, err
+		}
+
+		const cheat = false
+		for i := 0; i < nd*8; i++ {
+			pinfo := &pixByOff[doff+i]
+			pix := p.Pixel[pinfo.Y][pinfo.X]
+			if bb.B[i/8]&(1<<uint(7-i&7)) != 0 {
+				pix ^= coding.Black
+			}
+			expect[pinfo.Y][pinfo.X] = pix&coding.Black != 0
+			if cheat {
+				p.Pixel[pinfo.Y][pinfo.X] = pix & coding.Black
+			}
+		}
+		for i := 0; i < nc*8; i++ {
+			pinfo := &pixByOff[p.DataBytes*8+coff+i]
+			pix := p.Pixel[pinfo.Y][pinfo.X]
+			if bb.B[nd+i/8]&(1<<uint(7-i&7)) != 0 {
+				pix ^= coding.Black
+			}
+			expect[pinfo.Y][pinfo.X] = pix&coding.Black != 0
+			if cheat {
+				p.Pixel[pinfo.Y][pinfo.X] = pix & coding.Black
+			}
+		}
+		doff += nd * 8
+		coff += nc * 8
+	}
+
+	// Pass over all pixels again, dithering.
+	if m.Dither {
+		for i := range pixByOff {
+			pinfo := &pixByOff[i]
+			pinfo.DTarg = int(pinfo.Targ)
+		}
+		for y, row := range p.Pixel {
+			for x, pix := range row {
+				if pix.Role() != coding.Data && pix.Role() != coding.Check {
+					continue
+				}
+				pinfo := &pixByOff[pix.Offset()]
+				if pinfo.Block == nil {
+					// did not choose this pixel
+					continue
+				}
+
+				pix := pinfo.Pix
+
+				pval := byte(1) // pixel value (black)
+				v := 0          // gray value (black)
+				targ := pinfo.DTarg
+				if targ >= 128 {
+					// want white
+					pval = 0
+					v = 255
+				}
+
+				bval := pval // bit value
+				if pix&coding.Invert != 0 {
+					bval ^= 1
+				}
+				if pinfo.HardZero && bval != 0 {
+					bval ^= 1
+					pval ^= 1
+					v ^= 255
+				}
+
+				// Set pixel value as we want it.
+				pinfo.Block.reset(pinfo.Bit, bval)
+
+				_, _ = x, y
+
+				err := targ - v
+				if x+1 < len(row) {
+					addDither(pixByOff, row[x+1], err*7/16)
+				}
+				if false && y+1 < len(p.Pixel) {
+					if x > 0 {
+						addDither(pixByOff, p.Pixel[y+1][x-1], err*3/16)
+					}
+					addDither(pixByOff, p.Pixel[y+1][x], err*5/16)
+					if x+1 < len(row) {
+						addDither(pixByOff, p.Pixel[y+1][x+1], err*1/16)
+					}
+				}
+			}
+		}
+
+		for _, bb := range bitblocks {
+			if err := bb.copyOut(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	noops := 0
+	// Copy free characters back out.
+	for i := 0; i < (mbit-bbit)/g.bits; i++ {
+		// Pull out one group of bits.
+		v := 0
+		for j := 0; j < g.bits; j++ {
+			bi := uint(bbit + g.bits*i + j)
+			v <<= 1
+			v |= int((data[bi/8] >> (7 - bi&7)) & 1)
+		}
+		if v >= g.limit {
+			// Oops - too many 1 bits.
+			// Clear one of the high bits, which are all set.
+			// This will break some checksum bits, but so be it.
+			pinfo := &pixByOff[bbit+g.bits*i+hardZero] // TODO random
+			pinfo.Contrast = hardContrast
+			pinfo.HardZero = true
+			noops++
+			continue
+		}
+		pl.Free.chars(l.free[i*g.chars:], v)
+	}
+	if noops > 0 {
+		goto Again
+	}
+
+	if b1 := l.bits(p.Level); !bytes.Equal(b.Bytes(), b1.Bytes()) {
+		return nil, fmt.Errorf("qart: internal error: encoded bytes do not match layout")
+	}
+
+	cc, err := p.Encode(l.segments()...)
+	if err != nil {
+		return nil, err
+	}
+	m.Text = l.String()
+
+	if !m.Dither {
+		for y, row := range expect {
+			for x, pix := range row {
+				if cc.Black(x, y) != pix {
+					return nil, fmt.Errorf("qart: internal error: pixel %d,%d (%v) does not match plan", x, y, p.Pixel[y][x])
+				}
+			}
+		}
+	}
+
+	m.Code = &qr.Code{Bitmap: cc.Bitmap, Size: cc.Size, Stride: cc.Stride, Scale: m.scale()}
+
+	if m.SaveControl {
+		m.Control = pngEncode(makeImage(0, cc.Size, 4, m.scale(), func(x, y int) (rgba uint32) {
+			pix := p.Pixel[y][x]
+			if pix.Role() == coding.Data || pix.Role() == coding.Check {
+				pinfo := &pixByOff[pix.Offset()]
+				if pinfo.Block != nil {
+					if cc.Black(x, y) {
+						return 0x000000ff
+					}
+					return 0xffffffff
+				}
+			}
+			if cc.Black(x, y) {
+				return 0x3f3f3fff
+			}
+			return 0xbfbfbfff
+		}))
+	}
+
+	if m.SaveHalftone {
+		m.Halftone = pngEncode(m.halftone(p, cc))
+	}
+	if m.SaveColor {
+		m.Color = m.colorize(cc)
+	}
+	if m.SaveReport {
+		m.Report = m.report(p, cc, pixByOff)
+	}
+
+	return m.Code, nil
+}
+
+// Fidelity returns a score between 0 and 1 measuring how well
+// m.Code, the result of the last call to Encode, resembles the target.
+// It is the fraction of target pixels drawn in the right color,
+// with each pixel weighted by its contrast plus one, so that
+// edges and detail count for more than flat regions,
+// and by its weight in m.Weight, if any.
+func (m *Image) Fidelity() float64 {
+	if m.Code == nil {
+		return 0
+	}
+	var match, total int64
+	for y := 0; y < m.Code.Size; y++ {
+		for x := 0; x < m.Code.Size; x++ {
+			targ, contrast := m.target(x, y)
+			if contrast < 0 {
+				continue
+			}
+			w := int64(contrast) + 1
+			total += w
+			if m.Code.Black(x, y) == (targ < 128) {
+				match += w
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(match) / float64(total)
+}
+
+func addDither(pixByOff []Pixinfo, pix coding.Pixel, err int) {
+	if pix.Role() != coding.Data && pix.Role() != coding.Check {
+		return
+	}
+	pinfo := &pixByOff[pix.Offset()]
+	pinfo.DTarg += err
+}
@@ -0,0 +1,605 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qart
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"rsc.io/qr"
+	"rsc.io/qr/coding"
+	"rsc.io/qr/decode"
+	"rsc.io/qr/gf256"
+)
+
+// disk returns an n×n image of a black disk on a white background.
+func disk(n int) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, n, n))
+	r := n / 3
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			c := color.NRGBA{255, 255, 255, 255}
+			if dx, dy := x-n/2, y-n/2; dx*dx+dy*dy < r*r {
+				c = color.NRGBA{0, 0, 0, 255}
+			}
+			m.SetNRGBA(x, y, c)
+		}
+	}
+	return m
+}
+
+// agreement returns the fraction of the target pixels
+// that the code draws as the right color.
+func agreement(m *Image) float64 {
+	match, total := 0, 0
+	for y := 0; y < m.Code.Size; y++ {
+		for x := 0; x < m.Code.Size; x++ {
+			targ, contrast := m.target(x, y)
+			if contrast < 0 {
+				continue
+			}
+			total++
+			if m.Code.Black(x, y) == (targ < 128) {
+				match++
+			}
+		}
+	}
+	return float64(match) / float64(total)
+}
+
+func TestEncode(t *testing.T) {
+	for _, tt := range []struct {
+		version, mask, rotation int
+	}{
+		{6, 2, 0},
+		{4, 5, 1},
+		{8, 0, 3},
+	} {
+		m := &Image{
+			URL:      "https://research.swtch.com/qart",
+			Version:  tt.version,
+			Mask:     tt.mask,
+			Rotation: tt.rotation,
+		}
+		m.SetImage(disk(100))
+		c, err := m.Encode()
+		if err != nil {
+			t.Errorf("%+v: %v", tt, err)
+			continue
+		}
+		if want := 17 + 4*tt.version; c.Size != want {
+			t.Errorf("%+v: code size %d, want %d", tt, c.Size, want)
+		}
+		if a := agreement(m); a < 0.6 {
+			t.Errorf("%+v: code matches %.0f%% of target, want ≥60%%", tt, 100*a)
+		}
+	}
+}
+
+func TestControl(t *testing.T) {
+	m := &Image{URL: "https://example.com/", Version: 3, SaveControl: true}
+	m.SetImage(disk(50))
+	if _, err := m.Encode(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := png.Decode(bytes.NewReader(m.Control)); err != nil {
+		t.Fatalf("Control is not a PNG: %v", err)
+	}
+}
+
+func TestNoSource(t *testing.T) {
+	m := &Image{URL: "https://example.com/", Version: 3}
+	if _, err := m.Encode(); err == nil {
+		t.Fatal("Encode without source succeeded")
+	}
+}
+
+func TestInvalidParams(t *testing.T) {
+	for _, m := range []*Image{
+		{Version: 41},
+		{Version: -1},
+		{Version: 6, Level: 7},
+		{Version: 6, Level: -1},
+		{Version: 6, Mask: 12},
+		{Version: 6, Mask: -1},
+	} {
+		m.URL = "https://example.com/"
+		m.SetImage(disk(50))
+		want := *m
+		if _, err := m.Encode(); err == nil {
+			t.Errorf("Encode with version %d, level %d, mask %d succeeded", want.Version, want.Level, want.Mask)
+		}
+		if m.Version != want.Version || m.Level != want.Level || m.Mask != want.Mask {
+			t.Errorf("Encode changed version %d, level %d, mask %d to %d, %d, %d", want.Version, want.Level, want.Mask, m.Version, m.Level, m.Mask)
+		}
+		if _, err := m.Search(nil); err == nil {
+			t.Errorf("Search with version %d, level %d, mask %d succeeded", want.Version, want.Level, want.Mask)
+		}
+	}
+
+	// A zero version means version 1.
+	var m Image
+	m.Clamp()
+	if m.Version != 1 {
+		t.Errorf("Clamp set version %d, want 1", m.Version)
+	}
+
+	// Small uses the image scale.
+	for _, tt := range []struct {
+		version, scale int
+		small          bool
+	}{
+		{1, 0, true},
+		{40, 0, true}, // scale 2
+		{12, 4, true},
+		{12, 8, false},
+	} {
+		m := &Image{Version: tt.version, Scale: tt.scale}
+		if m.Small() != tt.small {
+			t.Errorf("version %d scale %d: Small() = %v, want %v", tt.version, tt.scale, !tt.small, tt.small)
+		}
+	}
+}
+
+func TestFidelity(t *testing.T) {
+	m := &Image{URL: "https://research.swtch.com/qart", Version: 6, Mask: 2}
+	m.SetImage(disk(100))
+	if _, err := m.Encode(); err != nil {
+		t.Fatal(err)
+	}
+	f := m.Fidelity()
+	if f < 0.6 || f > 1 {
+		t.Errorf("Fidelity() = %.3f, want between 0.6 and 1", f)
+	}
+
+	// Inverting the code must make it worse.
+	for i := range m.Code.Bitmap {
+		m.Code.Bitmap[i] = ^m.Code.Bitmap[i]
+	}
+	if g := m.Fidelity(); g >= f {
+		t.Errorf("Fidelity() of inverted code = %.3f, want < %.3f", g, f)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	for _, tt := range []struct {
+		version int
+		level   qr.Level
+		scale   int
+	}{
+		{5, qr.M, 8},
+		{11, qr.Q, 8},
+		{12, qr.H, 4},
+		{25, qr.M, 4},
+		{40, qr.H, 2},
+	} {
+		m := &Image{
+			URL:     "https://research.swtch.com/qart",
+			Version: tt.version,
+			Level:   tt.level,
+			Mask:    5,
+			Dither:  tt.version == 25,
+		}
+		m.SetImage(disk(100))
+		c, err := m.Encode()
+		if err != nil {
+			t.Errorf("%+v: %v", tt, err)
+			continue
+		}
+		if want := 17 + 4*tt.version; c.Size != want {
+			t.Errorf("%+v: code size %d, want %d", tt, c.Size, want)
+		}
+		if c.Scale != tt.scale {
+			t.Errorf("%+v: scale %d, want %d", tt, c.Scale, tt.scale)
+		}
+		if f := m.Fidelity(); f < 0.6 {
+			t.Errorf("%+v: Fidelity() = %.3f, want ≥0.6", tt, f)
+		}
+	}
+}
+
+func TestSeed(t *testing.T) {
+	encode := func(seed int64) *Image {
+		m := &Image{
+			URL:     "https://research.swtch.com/qart",
+			Version: 6,
+			Mask:    2,
+			Rand:    true,
+			Dither:  true,
+			Seed:    seed,
+		}
+		m.SetImage(disk(100))
+		if _, err := m.Encode(); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	m1, m2 := encode(12345), encode(12345)
+	if !bytes.Equal(m1.Code.Bitmap, m2.Code.Bitmap) {
+		t.Errorf("same seed produced different codes")
+	}
+	if m1.UsedSeed != 12345 {
+		t.Errorf("UsedSeed = %d, want 12345", m1.UsedSeed)
+	}
+	if m3 := encode(54321); bytes.Equal(m1.Code.Bitmap, m3.Code.Bitmap) {
+		t.Errorf("different seeds produced the same code")
+	}
+
+	// With no seed, the seed used must reproduce the code.
+	m4 := encode(0)
+	if m4.UsedSeed == 0 {
+		t.Fatalf("UsedSeed = 0 after Encode with no seed")
+	}
+	if m5 := encode(m4.UsedSeed); !bytes.Equal(m4.Code.Bitmap, m5.Code.Bitmap) {
+		t.Errorf("UsedSeed %d did not reproduce code", m4.UsedSeed)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	m := &Image{
+		URL:     "https://swtch.com/",
+		Version: 3,
+		Dx:      2,
+		Dy:      2,
+		Seed:    1,
+	}
+	m.SetImage(disk(60))
+	cands, err := m.Search(&SearchOptions{Offset: 1, Top: 5, Workers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cands) != 5 {
+		t.Fatalf("Search returned %d candidates, want 5", len(cands))
+	}
+	for i, c := range cands {
+		if i > 0 && c.Mismatch < cands[i-1].Mismatch {
+			t.Errorf("candidates out of order: %v before %v", cands[i-1].Mismatch, c.Mismatch)
+		}
+		if c.Image == nil || c.Image.Code == nil {
+			t.Fatalf("candidate %d has no code", i)
+		}
+		if f := c.Image.Fidelity(); 1-f != c.Mismatch {
+			t.Errorf("candidate %d: Mismatch = %v, but re-encoded fidelity is %v", i, c.Mismatch, f)
+		}
+	}
+	if m.Dx != 2 || m.Dy != 2 || m.Size != 0 || m.Code != nil {
+		t.Errorf("Search modified its receiver")
+	}
+
+	// The best candidate must be at least as good as
+	// the original placement with any mask or rotation.
+	for rot := 0; rot < 4; rot++ {
+		for mask := 0; mask < 8; mask++ {
+			c := *m
+			c.Rotation, c.Mask = rot, mask
+			if _, err := c.Encode(); err != nil {
+				t.Fatal(err)
+			}
+			if f := c.Fidelity(); 1-f < cands[0].Mismatch {
+				t.Errorf("rotation %d mask %d: mismatch %v beats best %v", rot, mask, 1-f, cands[0].Mismatch)
+			}
+		}
+	}
+}
+
+func TestAlpha(t *testing.T) {
+	// Left half transparent, right half black at half opacity.
+	src := image.NewNRGBA(image.Rect(0, 0, 40, 40))
+	for y := 0; y < 40; y++ {
+		for x := 20; x < 40; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{0, 0, 0, 128})
+		}
+	}
+	for _, tt := range []struct {
+		alpha       int
+		left, right int
+	}{
+		{0, -1, 127},
+		{200, -1, -1},
+	} {
+		targ := makeTarg(src, 40, tt.alpha)
+		if l, r := targ[10][5], targ[10][35]; l != tt.left || r != tt.right {
+			t.Errorf("alpha %d: target %d, %d, want %d, %d", tt.alpha, l, r, tt.left, tt.right)
+		}
+	}
+}
+
+func TestTargetDontCare(t *testing.T) {
+	// A flat gray target next to don't-care pixels has no contrast:
+	// the don't-care pixels do not count as neighbors.
+	targ := make([][]int, 20)
+	for y := range targ {
+		targ[y] = make([]int, 20)
+		for x := range targ[y] {
+			targ[y][x] = 128
+			if x < 10 {
+				targ[y][x] = -1
+			}
+		}
+	}
+	m := &Image{Target: targ}
+	if v, c := m.target(12, 10); v != 128 || c != 0 {
+		t.Errorf("target(12, 10) = %d, %d, want 128, 0", v, c)
+	}
+	if _, c := m.target(5, 10); c != -1 {
+		t.Errorf("target(5, 10) contrast = %d, want -1", c)
+	}
+}
+
+func TestWeight(t *testing.T) {
+	// half returns a weight map that is white on the left or right half.
+	half := func(left bool) image.Image {
+		w := image.NewGray(image.Rect(0, 0, 100, 100))
+		for y := 0; y < 100; y++ {
+			for x := 0; x < 100; x++ {
+				if (x < 50) == left {
+					w.Pix[y*w.Stride+x] = 255
+				}
+			}
+		}
+		return w
+	}
+	// leftAgreement returns the agreement of the left half of the code.
+	leftAgreement := func(m *Image) float64 {
+		match, total := 0, 0
+		for y := 0; y < m.Code.Size; y++ {
+			for x := 0; x+m.Dx < targSize(m.Target)/2; x++ {
+				targ, contrast := m.target(x, y)
+				if contrast < 0 {
+					continue
+				}
+				total++
+				if m.Code.Black(x, y) == (targ < 128) {
+					match++
+				}
+			}
+		}
+		return float64(match) / float64(total)
+	}
+
+	var agree [2]float64
+	for i, left := range []bool{true, false} {
+		m := &Image{URL: "https://research.swtch.com/qart", Version: 6, Mask: 2, Seed: 1}
+		m.SetImage(disk(100))
+		m.SetWeight(half(left))
+		if _, err := m.Encode(); err != nil {
+			t.Fatal(err)
+		}
+		agree[i] = leftAgreement(m)
+	}
+	if agree[0] <= agree[1] {
+		t.Errorf("left half matches %.3f weighted left, %.3f weighted right; want better when weighted left", agree[0], agree[1])
+	}
+}
+
+func TestBlockError(t *testing.T) {
+	// Internal inconsistencies are errors, not panics.
+	rs := gf256.NewRSEncoder(coding.Field, 4)
+	data, check := []byte{1, 2, 3}, make([]byte, 4)
+	if _, err := newBlock(3, 4, rs, data, check); err == nil {
+		t.Errorf("newBlock with wrong check bytes succeeded")
+	}
+	rs.ECC(data, check)
+	b, err := newBlock(3, 4, rs, data, check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.reset(0, b.B[0]>>7^1)
+	if err := b.copyOut(); err == nil || !strings.Contains(err.Error(), "internal error") {
+		t.Errorf("copyOut after bad reset = %v, want internal error", err)
+	}
+}
+
+func TestHalftone(t *testing.T) {
+	m := &Image{URL: "https://research.swtch.com/qart", Version: 6, Mask: 2, Scale: 9, SaveHalftone: true}
+	m.SetImage(disk(100))
+	c, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(m.Halftone))
+	if err != nil {
+		t.Fatalf("Halftone is not a PNG: %v", err)
+	}
+	if d := (c.Size + 8) * 9; img.Bounds() != image.Rect(0, 0, d, d) {
+		t.Fatalf("Halftone bounds %v, want %dx%d", img.Bounds(), d, d)
+	}
+	black := func(x, y int) bool {
+		r, _, _, _ := img.At(x, y).RGBA()
+		return r < 0x8000
+	}
+	differ := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			// The center third of each module carries the bit.
+			for dy := 3; dy < 6; dy++ {
+				for dx := 3; dx < 6; dx++ {
+					if black((x+4)*9+dx, (y+4)*9+dy) != c.Black(x, y) {
+						t.Fatalf("module %d,%d: center pixel %d,%d does not match code", x, y, dx, dy)
+					}
+				}
+			}
+			if black((x+4)*9, (y+4)*9) != c.Black(x, y) {
+				differ++
+			}
+		}
+	}
+	if differ == 0 {
+		t.Errorf("module corners all match the code; want halftone image")
+	}
+}
+
+func TestHalftoneDecode(t *testing.T) {
+	// Scale 0 means the default, which is 4 at version 12.
+	for _, scale := range []int{0, 3, 4, 5, 6, 7, 8, 9} {
+		m := &Image{URL: "https://research.swtch.com/qart", Version: 12, Mask: 2, Scale: scale, Seed: 1, SaveHalftone: true}
+		m.SetImage(disk(100))
+		if _, err := m.Encode(); err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(m.Halftone))
+		if err != nil {
+			t.Fatalf("scale %d: %v", scale, err)
+		}
+		r, err := decode.Decode(img)
+		if err != nil {
+			t.Errorf("scale %d: decode: %v", scale, err)
+			continue
+		}
+		if r.Text != m.Text {
+			t.Errorf("scale %d: decoded %q, want %q", scale, r.Text, m.Text)
+		}
+	}
+}
+
+func TestColorLimits(t *testing.T) {
+	for _, tt := range []struct {
+		opt         *ColorOptions
+		dark, light int
+	}{
+		{nil, 80, 176},
+		{&ColorOptions{}, 80, 176},
+		{&ColorOptions{Threshold: 100, Margin: 10}, 90, 110},
+		{&ColorOptions{Margin: -1}, 128, 128},
+		{&ColorOptions{Threshold: 20, Margin: 40}, 0, 60},
+	} {
+		if dark, light := tt.opt.limits(); dark != tt.dark || light != tt.light {
+			t.Errorf("%+v: limits() = %d, %d, want %d, %d", tt.opt, dark, light, tt.dark, tt.light)
+		}
+	}
+}
+
+func TestColor(t *testing.T) {
+	// A red disk on a pale blue background.
+	src := disk(100)
+	for i := 0; i < len(src.Pix); i += 4 {
+		if src.Pix[i] == 0 {
+			src.Pix[i] = 200
+		} else {
+			src.Pix[i], src.Pix[i+1] = 160, 200
+		}
+	}
+	opt := &ColorOptions{Threshold: 120, Margin: 40}
+	m := &Image{URL: "https://research.swtch.com/qart", Version: 6, Mask: 2, Scale: 4, SaveColor: true, ColorOptions: opt}
+	m.SetImage(src)
+	c, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(m.Color))
+	if err != nil {
+		t.Fatalf("Color is not a PNG: %v", err)
+	}
+	red := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			r, g, b, _ := img.At((x+4)*4, (y+4)*4).RGBA()
+			px := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 255}
+			if l := luma(px); c.Black(x, y) && l > 80 || !c.Black(x, y) && l < 160 {
+				t.Fatalf("module %d,%d: luminance %d out of range (black=%v)", x, y, l, c.Black(x, y))
+			}
+			if px.R > px.G && px.R > px.B {
+				red++
+			}
+		}
+	}
+	if red == 0 {
+		t.Errorf("no red modules; want hue from source")
+	}
+}
+
+func TestReport(t *testing.T) {
+	m := &Image{URL: "https://research.swtch.com/qart", Version: 6, Mask: 2, SaveReport: true}
+	m.SetImage(disk(100))
+	c, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := m.Report
+	if r.Size != c.Size || len(r.Modules) != c.Size*c.Size {
+		t.Fatalf("report size %d with %d modules, want %d", r.Size, len(r.Modules), c.Size)
+	}
+	if r.Fidelity != m.Fidelity() {
+		t.Errorf("report fidelity %v, want %v", r.Fidelity, m.Fidelity())
+	}
+	if r.Total.Modules != c.Size*c.Size {
+		t.Errorf("Total.Modules = %d, want %d", r.Total.Modules, c.Size*c.Size)
+	}
+	var sum Stats
+	for role, s := range r.ByRole {
+		if s.Controlled > 0 && role != coding.Data && role != coding.Check {
+			t.Errorf("%v: %d controlled modules, want 0", role, s.Controlled)
+		}
+		sum.Modules += s.Modules
+		sum.Controlled += s.Controlled
+		sum.Care += s.Care
+		sum.Matched += s.Matched
+	}
+	if sum != r.Total {
+		t.Errorf("sum of ByRole = %+v, want Total %+v", sum, r.Total)
+	}
+	if r.Total.Controlled == 0 || r.Total.Matched == 0 || r.Total.Mismatched() == 0 {
+		t.Errorf("Total = %+v, want some controlled, matched, and mismatched modules", r.Total)
+	}
+	if got := float64(r.Total.Matched) / float64(r.Total.Care); got != agreement(m) {
+		t.Errorf("matched fraction %v, want %v", got, agreement(m))
+	}
+	if !strings.Contains(r.String(), "data") {
+		t.Errorf("String() = %q, missing data row", r.String())
+	}
+	if d := (c.Size + 8) * 3; r.HeatMap(3).Bounds() != image.Rect(0, 0, d, d) {
+		t.Errorf("HeatMap(3) bounds %v, want %dx%d", r.HeatMap(3).Bounds(), d, d)
+	}
+}
+
+func TestSetText(t *testing.T) {
+	m := &Image{URL: "https://example.com/", Version: 10, Mask: 2, Seed: 1}
+	if err := m.SetText("GO", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.glyph) == 0 {
+		t.Fatal("SetText drew no strokes")
+	}
+	if _, err := m.Encode(); err != nil {
+		t.Fatal(err)
+	}
+	if miss := m.GlyphMisses(); len(miss) > len(m.glyph)/10 {
+		t.Errorf("missed %d of %d glyph modules", len(miss), len(m.glyph))
+	}
+
+	// Moving and scaling the code after SetText, as Search does,
+	// moves the strokes GlyphMisses checks along with the target.
+	m.Dx, m.Dy, m.Size = 6, 5, 10
+	if _, err := m.Encode(); err != nil {
+		t.Fatal(err)
+	}
+	if miss := m.GlyphMisses(); len(miss) > len(m.glyph)/10 {
+		t.Errorf("after moving code: missed %d of %d glyph modules", len(miss), len(m.glyph))
+	}
+
+	// Strokes that fall outside the target are not recorded.
+	m.Dx, m.Dy, m.Size = -20, 0, 0
+	if err := m.SetText("GO", nil); err != nil {
+		t.Fatal(err)
+	}
+	dt := m.Source.Bounds().Dx()
+	for _, p := range m.glyph {
+		if p.X < 0 || p.X >= dt || p.Y < 0 || p.Y >= dt {
+			t.Fatalf("glyph point %v outside %d×%d target", p, dt, dt)
+		}
+	}
+
+	if err := m.SetText("this text is far too long to fit", nil); err == nil {
+		t.Error("SetText of long text succeeded")
+	}
+	if err := m.SetText("π", nil); err == nil {
+		t.Error("SetText of missing glyph succeeded")
+	}
+}
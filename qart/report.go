@@ -0,0 +1,155 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qart
+
+import (
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+
+	"rsc.io/qr/coding"
+)
+
+// A Report describes how well an encoded code matches its target,
+// module by module.
+type Report struct {
+	Size    int      // number of modules on a side
+	Modules []Module // Size×Size modules, row by row
+
+	// Total summarizes all modules; ByRole summarizes
+	// the modules with each role.
+	Total  Stats
+	ByRole map[coding.PixelRole]*Stats
+
+	// Fidelity is the fraction of target modules drawn in the
+	// right color, weighted by contrast, as returned by Image.Fidelity.
+	Fidelity float64
+}
+
+// A Module describes a single module in a Report.
+type Module struct {
+	Role       coding.PixelRole
+	Black      bool // module is black in the code
+	Controlled bool // Encode chose the module's color to match the target
+	Care       bool // target has a color for the module
+	Target     byte // target gray value, if Care
+	Contrast   int  // target contrast (and weight), if Care
+}
+
+// Match reports whether the module has the target color.
+// Modules without a target color always match.
+func (mod *Module) Match() bool {
+	return !mod.Care || mod.Black == (mod.Target < 128)
+}
+
+// Stats counts modules in a Report.
+type Stats struct {
+	Modules    int // all modules
+	Controlled int // modules chosen by Encode
+	Care       int // modules with a target color
+	Matched    int // modules with a target color, drawn in that color
+}
+
+// Mismatched returns the number of modules with a target color
+// drawn in the opposite color.
+func (s *Stats) Mismatched() int {
+	return s.Care - s.Matched
+}
+
+func (s *Stats) add(mod *Module) {
+	s.Modules++
+	if mod.Controlled {
+		s.Controlled++
+	}
+	if mod.Care {
+		s.Care++
+		if mod.Match() {
+			s.Matched++
+		}
+	}
+}
+
+// report returns the report for the code cc, laid out by p,
+// with pixByOff recording the controlled pixels.
+func (m *Image) report(p *coding.Plan, cc *coding.Code, pixByOff []Pixinfo) *Report {
+	r := &Report{
+		Size:     cc.Size,
+		Modules:  make([]Module, cc.Size*cc.Size),
+		ByRole:   make(map[coding.PixelRole]*Stats),
+		Fidelity: m.Fidelity(),
+	}
+	for y, row := range p.Pixel {
+		for x, pix := range row {
+			mod := &r.Modules[y*cc.Size+x]
+			mod.Role = pix.Role()
+			mod.Black = cc.Black(x, y)
+			if mod.Role == coding.Data || mod.Role == coding.Check {
+				mod.Controlled = pixByOff[pix.Offset()].Block != nil
+			}
+			targ, contrast := m.target(x, y)
+			if contrast >= 0 {
+				mod.Care, mod.Target, mod.Contrast = true, targ, contrast
+			}
+			r.Total.add(mod)
+			s := r.ByRole[mod.Role]
+			if s == nil {
+				s = new(Stats)
+				r.ByRole[mod.Role] = s
+			}
+			s.add(mod)
+		}
+	}
+	return r
+}
+
+// String returns a table of the report's statistics.
+func (r *Report) String() string {
+	var roles []coding.PixelRole
+	for role := range r.ByRole {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i] < roles[j] })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "fidelity %.2f%%\n", 100*r.Fidelity)
+	fmt.Fprintf(&b, "%-10s %7s %10s %7s %7s %10s\n", "role", "modules", "controlled", "target", "matched", "mismatched")
+	line := func(name string, s *Stats) {
+		fmt.Fprintf(&b, "%-10s %7d %10d %7d %7d %10d\n", name, s.Modules, s.Controlled, s.Care, s.Matched, s.Mismatched())
+	}
+	for _, role := range roles {
+		line(role.String(), r.ByRole[role])
+	}
+	line("total", &r.Total)
+	return b.String()
+}
+
+// HeatMap returns an image of the report with a 4-module border,
+// drawing each module as a scale×scale square. Matched modules are
+// green and mismatched modules red, brighter for higher contrast.
+// Modules that Encode did not control are drawn in duller colors,
+// and modules without a target color are gray.
+func (r *Report) HeatMap(scale int) *image.RGBA {
+	max := 1
+	for i := range r.Modules {
+		if c := r.Modules[i].Contrast; c > max {
+			max = c
+		}
+	}
+	return makeImage(0, r.Size, 4, scale, func(x, y int) uint32 {
+		mod := &r.Modules[y*r.Size+x]
+		if !mod.Care {
+			return 0xe0e0e0ff
+		}
+		v := uint32(96 + 159*int64(mod.Contrast)/int64(max))
+		if !mod.Controlled {
+			v /= 2
+		}
+		if mod.Match() {
+			return v<<16 | 0xff
+		}
+		return v<<24 | 0xff
+	})
+}
@@ -0,0 +1,167 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qart
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SearchOptions describe the placements tried by Search.
+// Every placement is tried with all four rotations and all eight masks.
+type SearchOptions struct {
+	Offset  int // try Dx and Dy within ±Offset of the image's Dx and Dy
+	Size    int // try Size within ±Size of the image's Size
+	Top     int // number of results to return; 0 means 1
+	Workers int // number of goroutines; 0 means runtime.GOMAXPROCS(0)
+}
+
+// A Candidate is a placement found by Search.
+type Candidate struct {
+	Dx       int
+	Dy       int
+	Size     int
+	Rotation int
+	Mask     int
+
+	// Mismatch is the contrast-weighted fraction of target pixels
+	// that the code draws in the wrong color: 1 - Fidelity.
+	Mismatch float64
+
+	// Image is a copy of the original image with this placement,
+	// already encoded.
+	Image *Image
+}
+
+// Search encodes m with every placement described by opt and
+// returns the best ones, in order of increasing mismatch.
+// Search does not modify m. If m.Seed is zero, Search picks a
+// single seed for all placements, so that each result can be
+// reproduced by encoding its Image again.
+func (m *Image) Search(opt *SearchOptions) ([]*Candidate, error) {
+	var o SearchOptions
+	if opt != nil {
+		o = *opt
+	}
+	if o.Top <= 0 {
+		o.Top = 1
+	}
+	if o.Workers <= 0 {
+		o.Workers = runtime.GOMAXPROCS(0)
+	}
+
+	if m.Source == nil {
+		return nil, errNoSource
+	}
+	base := *m
+	base.Clamp()
+	if err := base.check(); err != nil {
+		return nil, err
+	}
+	base.SaveControl = false
+	base.SaveHalftone = false
+	base.SaveColor = false
+	base.SaveReport = false
+	if base.Seed == 0 {
+		base.Seed = time.Now().UnixNano()
+	}
+
+	// Compute the target for each size once, up front,
+	// instead of in every Encode.
+	targets := make(map[int][][]int)
+	weights := make(map[int][][]int)
+	for size := base.Size - o.Size; size <= base.Size+o.Size; size++ {
+		dt := 17 + 4*base.Version + size
+		if dt > 0 {
+			targets[size] = makeTarg(base.Source, dt, int(base.AlphaThreshold))
+			if base.Weight != nil {
+				weights[size] = makeTarg(base.Weight, dt, 0)
+			}
+		}
+	}
+
+	var cands []*Candidate
+	for size := base.Size - o.Size; size <= base.Size+o.Size; size++ {
+		if targets[size] == nil {
+			continue
+		}
+		for dy := base.Dy - o.Offset; dy <= base.Dy+o.Offset; dy++ {
+			for dx := base.Dx - o.Offset; dx <= base.Dx+o.Offset; dx++ {
+				for rot := 0; rot < 4; rot++ {
+					for mask := 0; mask < 8; mask++ {
+						cands = append(cands, &Candidate{Dx: dx, Dy: dy, Size: size, Rotation: rot, Mask: mask})
+					}
+				}
+			}
+		}
+	}
+
+	// Score all candidates in parallel.
+	place := func(c *Candidate) *Image {
+		i := base
+		i.Dx, i.Dy, i.Size, i.Rotation, i.Mask = c.Dx, c.Dy, c.Size, c.Rotation, c.Mask
+		i.Target = targets[c.Size]
+		i.weights = weights[c.Size]
+		return &i
+	}
+	errs := make([]error, len(cands))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < o.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				c := cands[i]
+				im := place(c)
+				if _, err := im.Encode(); err != nil {
+					errs[i] = err
+					continue
+				}
+				c.Mismatch = 1 - im.Fidelity()
+			}
+		}()
+	}
+	for i := range cands {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	var ok []*Candidate
+	var err error
+	for i, c := range cands {
+		if errs[i] != nil {
+			if err == nil {
+				err = errs[i]
+			}
+			continue
+		}
+		ok = append(ok, c)
+	}
+	if len(ok) == 0 && err != nil {
+		return nil, err
+	}
+	sort.SliceStable(ok, func(i, j int) bool { return ok[i].Mismatch < ok[j].Mismatch })
+	if len(ok) > o.Top {
+		ok = ok[:o.Top]
+	}
+
+	// Encode the winners again to keep their codes,
+	// and control images if requested.
+	for _, c := range ok {
+		c.Image = place(c)
+		c.Image.SaveControl = m.SaveControl
+		c.Image.SaveHalftone = m.SaveHalftone
+		c.Image.SaveColor = m.SaveColor
+		c.Image.SaveReport = m.SaveReport
+		if _, err := c.Image.Encode(); err != nil {
+			return nil, err
+		}
+	}
+	return ok, nil
+}
@@ -9,6 +9,9 @@
 // and this program is running at https://research.swtch.com/qr/draw/.
 //
 // To run the program locally, use “go run local.go”.
+//
+// The code generation itself is in package rsc.io/qr/qart;
+// this program is only the user interface.
 package main
 
 import (
@@ -20,8 +23,12 @@ import (
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
+	"image/png"
 	"strings"
 	"syscall/js"
+
+	"rsc.io/qr/qart"
+	"rsc.io/qr/resize"
 )
 
 //go:embed pjw.png
@@ -39,8 +46,7 @@ var (
 	inputURL js.Value // url box
 )
 
-var pic = &Image{
-	File:    pjwPNG,
+var pic = &qart.Image{
 	Dx:      4,
 	Dy:      4,
 	URL:     "https://research.swtch.com/qart",
@@ -48,6 +54,15 @@ var pic = &Image{
 	Mask:    2,
 }
 
+// srcPNG is a small PNG of pic.Source, for display.
+var srcPNG []byte
+
+func init() {
+	if err := pic.SetFile(pjwPNG); err != nil {
+		panic(err)
+	}
+}
+
 func up()       { pic.Dy++ }
 func down()     { pic.Dy-- }
 func left()     { pic.Dx++ }
@@ -57,7 +72,7 @@ func ismaller() { pic.Size-- }
 func rotate()   { pic.Rotation = (pic.Rotation + 1) & 3 }
 
 func bigger() {
-	if pic.Version < 8 {
+	if pic.Version < 40 {
 		pic.Version++
 	}
 }
@@ -82,7 +97,14 @@ func update() {
 	pic.Dither = checkDither.Get("checked").Bool()
 	pic.SaveControl = checkControl.Get("checked").Bool()
 	pic.URL = inputURL.Get("value").String()
-	img, err := pic.Encode()
+	var img []byte
+	code, err := pic.Encode()
+	if err == nil {
+		img = code.PNG()
+		if pic.SaveControl {
+			img = pic.Control
+		}
+	}
 	setImage("img-output", img)
 	doc.Call("getElementById", "img-download").Set("href", "data:image/png;base64,"+base64.StdEncoding.EncodeToString(img))
 	if err != nil {
@@ -90,6 +112,30 @@ func update() {
 	}
 }
 
+// src returns a small PNG of pic.Source, for display.
+func src() []byte {
+	if srcPNG == nil {
+		const max = 48
+		i := pic.Source
+		b := i.Bounds()
+		dx, dy := max, max
+		if b.Dx() > b.Dy() {
+			dy = b.Dy() * dx / b.Dx()
+		} else {
+			dx = b.Dx() * dy / b.Dy()
+		}
+		small := resize.Resize(i, b, dx, dy, nil)
+		srcPNG = pngEncode(small)
+	}
+	return srcPNG
+}
+
+func pngEncode(c image.Image) []byte {
+	var b bytes.Buffer
+	png.Encode(&b, c)
+	return b.Bytes()
+}
+
 func funcOf(f func()) js.Func {
 	return js.FuncOf(func(_ js.Value, _ []js.Value) any {
 		f()
@@ -122,11 +168,7 @@ func main() {
 	doc.Call("getElementById", "wasm1").Get("style").Set("display", "block")
 	doc.Call("getElementById", "wasm2").Get("style").Set("display", "block")
 
-	if img, err := pic.Src(); err == nil {
-		setImage("img-src", img)
-	} else {
-		setErr(err)
-	}
+	setImage("img-src", src())
 
 	do := func(id string, f func()) {
 		doc.Call("getElementById", id).Set("onclick", funcOf(func() { f(); update() }))
@@ -169,18 +211,12 @@ func main() {
 				fmt.Println(len(data))
 				fmt.Printf("%q\n", data[:20])
 
-				_, _, err = image.Decode(bytes.NewReader(data))
-				if err != nil {
-					setErr(err)
-					return nil
-				}
-				pic.SetFile(data)
-				img, err := pic.Src()
-				if err != nil {
+				if err := pic.SetFile(data); err != nil {
 					setErr(err)
 					return nil
 				}
-				setImage("img-src", img)
+				srcPNG = nil
+				setImage("img-src", src())
 				update()
 				return nil
 			})
@@ -27,45 +27,125 @@ const (
 )
 
 // Encode returns an encoding of text at the given error correction level.
+// It uses the smallest version that fits and always uses mask 0,
+// so its output does not change from one release to the next.
+// To pick the mask with the lowest penalty instead, use EncodeOptions
+// with Mask set to AutoMask.
 func Encode(text string, level Level) (*Code, error) {
+	c, _, err := EncodeOptions(text, level, nil)
+	return c, err
+}
+
+// Options controls the encoding chosen by EncodeOptions.
+// A nil *Options means the zero Options, which is what Encode uses.
+type Options struct {
+	MinVersion int // smallest QR version to use; 0 means 1
+	MaxVersion int // largest QR version to use; 0 means 40
+	Mask       int // QR mask to use, from 0 to 7, or AutoMask
+}
+
+// AutoMask, used as Options.Mask, says to use the mask that gives
+// the code the lowest penalty score, as defined by the QR standard.
+// The penalties discourage long runs, large blocks of a single color,
+// patterns resembling the position squares, and unbalanced
+// numbers of black and white pixels, all of which make
+// codes harder to read.
+const AutoMask = -1
+
+// Info describes the encoding chosen by EncodeOptions.
+type Info struct {
+	Version  int    // QR version, from 1 to 40
+	Level    Level  // error correction level
+	Mask     int    // QR mask, from 0 to 7
+	Mode     string // data encoding: "numeric", "alphanumeric", or "byte"
+	Bits     int    // number of data bits used
+	Capacity int    // number of data bits available
+}
+
+// EncodeOptions returns an encoding of text at the given error correction level,
+// using the options in opt, along with a description of the encoding.
+func EncodeOptions(text string, level Level, opt *Options) (*Code, *Info, error) {
+	var o Options
+	if opt != nil {
+		o = *opt
+	}
+	if level < L || level > H {
+		return nil, nil, errors.New("invalid QR level")
+	}
+	if o.MinVersion == 0 {
+		o.MinVersion = coding.MinVersion
+	}
+	if o.MaxVersion == 0 {
+		o.MaxVersion = coding.MaxVersion
+	}
+	if o.MinVersion < coding.MinVersion || o.MaxVersion > coding.MaxVersion || o.MinVersion > o.MaxVersion {
+		return nil, nil, errors.New("invalid QR version range")
+	}
+	if o.Mask < AutoMask || o.Mask > 7 {
+		return nil, nil, errors.New("invalid QR mask")
+	}
+
 	// Pick data encoding, smallest first.
 	// We could split the string and use different encodings
 	// but that seems like overkill for now.
 	var enc coding.Encoding
+	var mode string
 	switch {
 	case coding.Num(text).Check() == nil:
-		enc = coding.Num(text)
+		enc, mode = coding.Num(text), "numeric"
 	case coding.Alpha(text).Check() == nil:
-		enc = coding.Alpha(text)
+		enc, mode = coding.Alpha(text), "alphanumeric"
 	default:
-		enc = coding.String(text)
+		enc, mode = coding.String(text), "byte"
 	}
 
 	// Pick size.
 	l := coding.Level(level)
 	var v coding.Version
-	for v = coding.MinVersion; ; v++ {
-		if v > coding.MaxVersion {
-			return nil, errors.New("text too long to encode as QR")
+	for v = coding.Version(o.MinVersion); ; v++ {
+		if v > coding.Version(o.MaxVersion) {
+			return nil, nil, errors.New("text too long to encode as QR")
 		}
 		if enc.Bits(v) <= v.DataBytes(l)*8 {
 			break
 		}
 	}
 
-	// Build and execute plan.
-	p, err := coding.NewPlan(v, l, 0)
-	if err != nil {
-		return nil, err
+	// Build and execute plan for each mask,
+	// keeping the one with the lowest penalty.
+	masks := []int{o.Mask}
+	if o.Mask == AutoMask {
+		masks = []int{0, 1, 2, 3, 4, 5, 6, 7}
 	}
-	cc, err := p.Encode(enc)
-	if err != nil {
-		return nil, err
+	var best *coding.Code
+	bestMask, bestPenalty := 0, 0
+	for _, mask := range masks {
+		p, err := coding.NewPlan(v, l, coding.Mask(mask))
+		if err != nil {
+			return nil, nil, err
+		}
+		cc, err := p.Encode(enc)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(masks) == 1 {
+			best, bestMask = cc, mask
+			break
+		}
+		if pen := penalty(cc); best == nil || pen < bestPenalty {
+			best, bestMask, bestPenalty = cc, mask, pen
+		}
 	}
 
-	// TODO: Pick appropriate mask.
-
-	return &Code{cc.Bitmap, cc.Size, cc.Stride, 8}, nil
+	info := &Info{
+		Version:  int(v),
+		Level:    level,
+		Mask:     bestMask,
+		Mode:     mode,
+		Bits:     enc.Bits(v),
+		Capacity: v.DataBytes(l) * 8,
+	}
+	return &Code{Bitmap: best.Bitmap, Size: best.Size, Stride: best.Stride, Scale: 8}, info, nil
 }
 
 // A Code is a square pixel grid.
@@ -0,0 +1,124 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qr
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"rsc.io/qr/coding"
+)
+
+func TestEncodeOptions(t *testing.T) {
+	for _, tt := range []struct {
+		text    string
+		level   Level
+		opt     *Options
+		version int
+		mode    string
+	}{
+		{"01234567", L, nil, 1, "numeric"},
+		{"HELLO WORLD", M, nil, 1, "alphanumeric"},
+		{"hello, world", H, nil, 2, "byte"},
+		{"hello, world", L, &Options{MinVersion: 5}, 5, "byte"},
+		{"hello, world", L, &Options{Mask: 6}, 1, "byte"},
+	} {
+		c, info, err := EncodeOptions(tt.text, tt.level, tt.opt)
+		if err != nil {
+			t.Errorf("%q: %v", tt.text, err)
+			continue
+		}
+		mask := 0
+		if tt.opt != nil {
+			mask = tt.opt.Mask
+		}
+		if info.Version != tt.version || info.Mode != tt.mode || info.Level != tt.level || info.Mask != mask {
+			t.Errorf("%q: info = %+v, want version %d, mode %s, level %d, mask %d", tt.text, info, tt.version, tt.mode, tt.level, mask)
+		}
+		if c.Size != 17+4*tt.version {
+			t.Errorf("%q: size %d, want %d", tt.text, c.Size, 17+4*tt.version)
+		}
+		if info.Bits > info.Capacity || info.Capacity != coding.Version(tt.version).DataBytes(coding.Level(tt.level))*8 {
+			t.Errorf("%q: %d bits of %d", tt.text, info.Bits, info.Capacity)
+		}
+	}
+
+	// Encode uses the default options.
+	c1, err := Encode("hello, world", L)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, _, err := EncodeOptions("hello, world", L, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(c1.Bitmap, c2.Bitmap) {
+		t.Errorf("Encode and EncodeOptions(nil) differ")
+	}
+
+	// Encode's output matches a direct mask 0 encoding
+	// in the smallest version, as it always has.
+	for _, text := range []string{"01234567", "HELLO WORLD", "hello, world", strings.Repeat("hello, world ", 50)} {
+		c, err := Encode(text, M)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var enc coding.Encoding = coding.String(text)
+		if coding.Num(text).Check() == nil {
+			enc = coding.Num(text)
+		} else if coding.Alpha(text).Check() == nil {
+			enc = coding.Alpha(text)
+		}
+		v := coding.Version(coding.MinVersion)
+		for enc.Bits(v) > v.DataBytes(coding.M)*8 {
+			v++
+		}
+		p, err := coding.NewPlan(v, coding.M, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cc, err := p.Encode(enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Size != cc.Size || !bytes.Equal(c.Bitmap, cc.Bitmap) {
+			t.Errorf("Encode(%.20q) differs from mask 0 encoding in version %d", text, v)
+		}
+	}
+
+	for _, opt := range []*Options{
+		{MaxVersion: 1},
+		{MinVersion: 3, MaxVersion: 2},
+		{MaxVersion: 41},
+		{Mask: 8},
+	} {
+		if _, _, err := EncodeOptions("hello, world, this is a longer text", H, opt); err == nil {
+			t.Errorf("EncodeOptions(%+v) succeeded", opt)
+		}
+	}
+}
+
+func TestAutoMask(t *testing.T) {
+	const text = "https://research.swtch.com/qart"
+	c, info, err := EncodeOptions(text, M, &Options{Mask: AutoMask})
+	if err != nil {
+		t.Fatal(err)
+	}
+	best := penalty(&coding.Code{Bitmap: c.Bitmap, Size: c.Size, Stride: c.Stride})
+	for mask := 0; mask < 8; mask++ {
+		m, _, err := EncodeOptions(text, M, &Options{Mask: mask})
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := penalty(&coding.Code{Bitmap: m.Bitmap, Size: m.Size, Stride: m.Stride})
+		if p < best {
+			t.Errorf("mask %d has penalty %d, better than chosen mask %d with penalty %d", mask, p, info.Mask, best)
+		}
+		if mask == info.Mask && !bytes.Equal(m.Bitmap, c.Bitmap) {
+			t.Errorf("AutoMask code differs from mask %d code", mask)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package qrhttp serves QR code images over HTTP.
+//
+// The handler returned by Handler encodes the text given in the
+// request's query parameters:
+//
+//	text    text to encode (required)
+//	level   error correction level: L, M, Q, or H (default L)
+//	scale   image pixels per module (default 8)
+//	border  quiet zone width in modules (default 4)
+//	format  png or svg (default png)
+//
+// Requests for images wider than 2048 pixels, counting the border,
+// fail with 400 Bad Request; Options.MaxSize changes the limit.
+//
+// For example, with the handler installed at /qr,
+//
+//	GET /qr?text=hello&level=M&scale=4
+//
+// returns a PNG of the code for "hello".
+//
+// The image depends only on the parameters, so the handler sets
+// a strong ETag computed from them, answers matching If-None-Match
+// requests with 304 Not Modified, and allows long caching.
+package qrhttp // import "rsc.io/qr/qrhttp"
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"rsc.io/qr"
+)
+
+// Options configures a handler.
+// The limits keep the memory used by each request bounded.
+type Options struct {
+	MaxText   int // longest text accepted, in bytes; 0 means 1024
+	MaxScale  int // largest scale accepted; 0 means 16
+	MaxBorder int // widest border accepted; 0 means 16
+	MaxSize   int // widest image accepted, in pixels; 0 means 2048
+
+	// MaxAge is the max-age sent in the Cache-Control header.
+	// Zero means one year; negative means to send no-cache.
+	MaxAge time.Duration
+}
+
+const (
+	defaultMaxText   = 1024
+	defaultMaxScale  = 16
+	defaultMaxBorder = 16
+	defaultMaxSize   = 2048
+	defaultMaxAge    = 365 * 24 * time.Hour
+)
+
+// Handler returns a handler serving QR code images
+// as described in the package documentation.
+// A nil *Options means the default options.
+func Handler(opt *Options) http.Handler {
+	h := &handler{
+		maxText:   defaultMaxText,
+		maxScale:  defaultMaxScale,
+		maxBorder: defaultMaxBorder,
+		maxSize:   defaultMaxSize,
+		maxAge:    defaultMaxAge,
+	}
+	if opt != nil {
+		if opt.MaxText > 0 {
+			h.maxText = opt.MaxText
+		}
+		if opt.MaxScale > 0 {
+			h.maxScale = opt.MaxScale
+		}
+		if opt.MaxBorder > 0 {
+			h.maxBorder = opt.MaxBorder
+		}
+		if opt.MaxSize > 0 {
+			h.maxSize = opt.MaxSize
+		}
+		if opt.MaxAge != 0 {
+			h.maxAge = opt.MaxAge
+		}
+	}
+	return h
+}
+
+type handler struct {
+	maxText   int
+	maxScale  int
+	maxBorder int
+	maxSize   int
+	maxAge    time.Duration
+}
+
+// A request holds the validated parameters of a request.
+type request struct {
+	text   string
+	level  qr.Level
+	scale  int
+	border int
+	format string
+}
+
+// etag returns the strong entity tag for the image requested by r.
+func (r *request) etag() string {
+	h := sha256.New()
+	// Include a format version, to change if the output ever does.
+	fmt.Fprintf(h, "qr2\x00%d\x00%d\x00%d\x00%s\x00%s", r.level, r.scale, r.border, r.format, r.text)
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+func (h *handler) parse(q url.Values) (*request, error) {
+	get := q.Get
+	num := func(name string, def, max int) (int, error) {
+		s := get(name)
+		if s == "" {
+			return def, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > max {
+			return 0, fmt.Errorf("invalid %s %q: must be between 0 and %d", name, s, max)
+		}
+		return n, nil
+	}
+
+	r := &request{text: get("text")}
+	if r.text == "" {
+		return nil, fmt.Errorf("missing text")
+	}
+	if len(r.text) > h.maxText {
+		return nil, fmt.Errorf("text too long: %d bytes, limit %d", len(r.text), h.maxText)
+	}
+	switch l := strings.ToUpper(get("level")); l {
+	case "", "L":
+		r.level = qr.L
+	case "M":
+		r.level = qr.M
+	case "Q":
+		r.level = qr.Q
+	case "H":
+		r.level = qr.H
+	default:
+		return nil, fmt.Errorf("invalid level %q: must be L, M, Q, or H", l)
+	}
+	var err error
+	if r.scale, err = num("scale", 8, h.maxScale); err != nil {
+		return nil, err
+	}
+	if r.scale == 0 {
+		return nil, fmt.Errorf("invalid scale 0")
+	}
+	if r.border, err = num("border", 4, h.maxBorder); err != nil {
+		return nil, err
+	}
+	switch r.format = strings.ToLower(get("format")); r.format {
+	case "":
+		r.format = "png"
+	case "png", "svg":
+	default:
+		return nil, fmt.Errorf("invalid format %q: must be png or svg", r.format)
+	}
+	return r, nil
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r, err := h.parse(req.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, err := qr.Encode(r.text, r.level)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Check the size before rendering anything.
+	if d := (c.Size + 2*r.border) * r.scale; d > h.maxSize {
+		http.Error(w, fmt.Sprintf("image too large: %d pixels wide, limit %d", d, h.maxSize), http.StatusBadRequest)
+		return
+	}
+	c.Scale = r.scale
+
+	hdr := w.Header()
+	etag := r.etag()
+	hdr.Set("ETag", etag)
+	if h.maxAge < 0 {
+		hdr.Set("Cache-Control", "no-cache")
+	} else {
+		hdr.Set("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", int64(h.maxAge/time.Second)))
+	}
+	if match(req.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	var data []byte
+	switch r.format {
+	case "png":
+		hdr.Set("Content-Type", "image/png")
+		data = c.PNGBorder(r.border)
+	case "svg":
+		hdr.Set("Content-Type", "image/svg+xml")
+		data = c.SVGBorder(r.border)
+	}
+	hdr.Set("Content-Length", strconv.Itoa(len(data)))
+	hdr.Set("X-Content-Type-Options", "nosniff")
+	if req.Method == "HEAD" {
+		return
+	}
+	w.Write(data)
+}
+
+// match reports whether the If-None-Match header value
+// inm matches etag, using the weak comparison function
+// as RFC 7232 requires for If-None-Match.
+func match(inm, etag string) bool {
+	for _, t := range strings.Split(inm, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
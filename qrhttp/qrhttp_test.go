@@ -0,0 +1,173 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qrhttp
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"rsc.io/qr"
+	"rsc.io/qr/decode"
+)
+
+func get(t *testing.T, h http.Handler, url string, hdr ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("GET", url, nil)
+	for i := 0; i+1 < len(hdr); i += 2 {
+		req.Header.Set(hdr[i], hdr[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestPNG(t *testing.T) {
+	h := Handler(nil)
+	for _, tt := range []struct {
+		query  string
+		level  qr.Level
+		scale  int
+		border int
+	}{
+		{"text=hello", qr.L, 8, 4},
+		{"text=hello&level=h&scale=3&border=0", qr.H, 3, 0},
+		{"text=hello%2C+world&level=Q&scale=5&border=10&format=PNG", qr.Q, 5, 10},
+	} {
+		w := get(t, h, "/qr?"+tt.query)
+		if w.Code != 200 {
+			t.Fatalf("%s: status %d: %s", tt.query, w.Code, w.Body)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "image/png" {
+			t.Errorf("%s: Content-Type = %q", tt.query, ct)
+		}
+		m, err := png.Decode(bytes.NewReader(w.Body.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		r, err := decode.Decode(m)
+		if err != nil {
+			t.Fatalf("%s: decode: %v", tt.query, err)
+		}
+		if r.Level != tt.level {
+			t.Errorf("%s: level %v, want %v", tt.query, r.Level, tt.level)
+		}
+		n := 17 + 4*r.Version
+		if d := m.Bounds().Dx(); d != (n+2*tt.border)*tt.scale {
+			t.Errorf("%s: image width %d, want %d", tt.query, d, (n+2*tt.border)*tt.scale)
+		}
+		if want := strings.Contains(tt.query, "world"); want != strings.Contains(r.Text, "world") {
+			t.Errorf("%s: decoded %q", tt.query, r.Text)
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	h := Handler(nil)
+	for _, query := range []string{"text=svg&format=svg", "text=svg&format=svg&border=1&scale=2"} {
+		w := get(t, h, "/?"+query)
+		if w.Code != 200 {
+			t.Fatalf("%s: status %d: %s", query, w.Code, w.Body)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "image/svg+xml" {
+			t.Errorf("%s: Content-Type = %q", query, ct)
+		}
+		if !strings.HasPrefix(w.Body.String(), "<svg ") {
+			t.Errorf("%s: body does not start with <svg", query)
+		}
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	h := Handler(&Options{MaxText: 10, MaxScale: 4, MaxBorder: 2})
+	for _, query := range []string{
+		"",
+		"text=",
+		"text=01234567890",
+		"text=x&level=X",
+		"text=x&scale=0",
+		"text=x&scale=5",
+		"text=x&scale=-1",
+		"text=x&scale=big",
+		"text=x&border=3",
+		"text=x&format=gif",
+	} {
+		if w := get(t, h, "/?"+query); w.Code != http.StatusBadRequest {
+			t.Errorf("%q: status %d, want 400", query, w.Code)
+		}
+	}
+	if w := get(t, h, "/?text=0123456789&scale=4&border=2"); w.Code != 200 {
+		t.Errorf("at limits: status %d: %s", w.Code, w.Body)
+	}
+
+	// The image size limit counts the version, scale, and border.
+	h = Handler(&Options{MaxSize: 100})
+	if w := get(t, h, "/?text=x&scale=4&border=0"); w.Code != 200 {
+		t.Errorf("84 pixels: status %d: %s", w.Code, w.Body)
+	}
+	for _, query := range []string{"text=x&scale=4&border=3", "text=x&scale=5&border=0", "text=hello,+world,+this+is+a+longer+text&scale=4&border=0"} {
+		if w := get(t, h, "/?"+query); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "too large") {
+			t.Errorf("%q: status %d: %s, want 400 too large", query, w.Code, w.Body)
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/?text=x", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") == "" {
+		t.Errorf("POST: status %d, Allow %q", w.Code, w.Header().Get("Allow"))
+	}
+}
+
+func TestCache(t *testing.T) {
+	h := Handler(nil)
+	w := get(t, h, "/?text=cache&scale=2")
+	etag := w.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"`) {
+		t.Fatalf("ETag = %q, want strong tag", etag)
+	}
+	if cc := w.Header().Get("Cache-Control"); !strings.Contains(cc, "max-age=31536000") {
+		t.Errorf("Cache-Control = %q", cc)
+	}
+
+	// Same parameters, same tag; different parameters, different tag.
+	if w := get(t, h, "/?scale=2&text=cache&level=L"); w.Header().Get("ETag") != etag {
+		t.Errorf("equivalent request has ETag %q, want %q", w.Header().Get("ETag"), etag)
+	}
+	if w := get(t, h, "/?text=cache&scale=3"); w.Header().Get("ETag") == etag {
+		t.Errorf("different scale has same ETag")
+	}
+
+	for _, inm := range []string{etag, `"x", ` + etag, "W/" + etag, "*"} {
+		w := get(t, h, "/?text=cache&scale=2", "If-None-Match", inm)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: status %d, %d bytes", inm, w.Code, w.Body.Len())
+		}
+	}
+	if w := get(t, h, "/?text=cache&scale=2", "If-None-Match", `"other"`); w.Code != 200 {
+		t.Errorf("If-None-Match other: status %d", w.Code)
+	}
+
+	h = Handler(&Options{MaxAge: -1})
+	if cc := get(t, h, "/?text=x").Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("MaxAge -1: Cache-Control = %q", cc)
+	}
+}
+
+func TestServer(t *testing.T) {
+	srv := httptest.NewServer(Handler(nil))
+	defer srv.Close()
+	resp, err := http.Head(srv.URL + "/?text=head")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 || resp.ContentLength <= 0 {
+		t.Errorf("HEAD: status %d, length %d", resp.StatusCode, resp.ContentLength)
+	}
+}
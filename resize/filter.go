@@ -0,0 +1,151 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package resize
+
+import (
+	"math"
+	"strconv"
+)
+
+// A Filter is a resampling filter.
+type Filter int
+
+const (
+	// Box averages the source pixels covered by each
+	// destination pixel, weighted by the area covered.
+	Box Filter = iota
+
+	// Bilinear interpolates linearly between the two
+	// nearest source pixels in each direction.
+	Bilinear
+
+	// Bicubic is the Catmull-Rom cubic filter,
+	// which is sharper than Bilinear.
+	Bicubic
+
+	// Lanczos3 is the Lanczos filter with three lobes:
+	// the sharpest filter, but prone to slight ringing at edges.
+	Lanczos3
+)
+
+var filterNames = []string{
+	Box:      "box",
+	Bilinear: "bilinear",
+	Bicubic:  "bicubic",
+	Lanczos3: "lanczos3",
+}
+
+func (f Filter) String() string {
+	if 0 <= f && int(f) < len(filterNames) {
+		return filterNames[f]
+	}
+	return "Filter(" + strconv.Itoa(int(f)) + ")"
+}
+
+// support returns the radius of the filter kernel.
+func (f Filter) support() float64 {
+	switch f {
+	case Bilinear:
+		return 1
+	case Bicubic:
+		return 2
+	case Lanczos3:
+		return 3
+	}
+	return 0.5
+}
+
+// kernel returns the filter kernel at x.
+func (f Filter) kernel(x float64) float64 {
+	x = math.Abs(x)
+	switch f {
+	case Bilinear:
+		if x < 1 {
+			return 1 - x
+		}
+	case Bicubic:
+		// Catmull-Rom: B=0, C=1/2.
+		if x < 1 {
+			return (3*x-5)*x*x/2 + 1
+		}
+		if x < 2 {
+			return ((-x+5)*x-8)*x/2 + 2
+		}
+	case Lanczos3:
+		if x == 0 {
+			return 1
+		}
+		if x < 3 {
+			return sinc(x) * sinc(x/3)
+		}
+	}
+	return 0
+}
+
+func sinc(x float64) float64 {
+	x *= math.Pi
+	return math.Sin(x) / x
+}
+
+// A tap is a single source pixel index i and its weight w.
+type tap struct {
+	i int
+	w float32
+}
+
+// weights returns, for each of the m destination pixels,
+// the taps for computing it from the n source pixels.
+// The weights of each destination pixel sum to 1.
+func weights(f Filter, n, m int) [][]tap {
+	scale := float64(n) / float64(m)
+	ws := make([][]tap, m)
+	if f == Box || f < 0 || int(f) >= len(filterNames) {
+		// Average the source pixels covering [x*scale, (x+1)*scale).
+		for x := range ws {
+			lo, hi := float64(x)*scale, float64(x+1)*scale
+			for i := int(lo); i < n && float64(i) < hi; i++ {
+				w := math.Min(hi, float64(i+1)) - math.Max(lo, float64(i))
+				if w > 0 {
+					ws[x] = append(ws[x], tap{i, float32(w / scale)})
+				}
+			}
+		}
+		return ws
+	}
+
+	// When shrinking, widen the filter to cover all the source pixels.
+	fscale := math.Max(scale, 1)
+	support := f.support() * fscale
+	for x := range ws {
+		c := (float64(x)+0.5)*scale - 0.5
+		var taps []tap
+		sum := 0.0
+		for i := int(math.Ceil(c - support)); float64(i) <= c+support; i++ {
+			w := f.kernel((float64(i) - c) / fscale)
+			if w == 0 {
+				continue
+			}
+			// Extend the edge pixels.
+			j := i
+			if j < 0 {
+				j = 0
+			}
+			if j >= n {
+				j = n - 1
+			}
+			if k := len(taps) - 1; k >= 0 && taps[k].i == j {
+				taps[k].w += float32(w)
+			} else {
+				taps = append(taps, tap{j, float32(w)})
+			}
+			sum += w
+		}
+		for i := range taps {
+			taps[i].w = float32(float64(taps[i].w) / sum)
+		}
+		ws[x] = taps
+	}
+	return ws
+}
@@ -0,0 +1,191 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package resize
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// A source reads rows of an image slice as premultiplied
+// float32 values with nc channels: either red, green, blue, and alpha,
+// or just gray. Alpha ranges from 0 to 255. In gamma mode, the colors
+// are linear light, from 0 to 1, times alpha; otherwise they are
+// the image's own (premultiplied) values, from 0 to 255.
+type source struct {
+	w, h, nc int
+	row      func(y int, dst []float32)
+}
+
+// toLinear maps sRGB values to linear light.
+var toLinear [256]float32
+
+// fromLinear maps linear light, quantized to 1/4095, to sRGB values.
+var fromLinear [4096]uint8
+
+func init() {
+	for i := range toLinear {
+		v := float64(i) / 255
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		toLinear[i] = float32(v)
+	}
+	for i := range fromLinear {
+		v := float64(i) / 4095
+		if v <= 0.0031308 {
+			v *= 12.92
+		} else {
+			v = 1.055*math.Pow(v, 1/2.4) - 0.055
+		}
+		fromLinear[i] = uint8(v*255 + 0.5)
+	}
+}
+
+// unlinear returns the sRGB value for the linear light v.
+func unlinear(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return 255
+	}
+	return fromLinear[int(v*4095+0.5)]
+}
+
+// newSource returns a source for the slice r of m.
+// If nc is 1, m must be an *image.Gray.
+func newSource(m image.Image, r image.Rectangle, nc int, gamma bool) *source {
+	s := &source{w: r.Dx(), h: r.Dy(), nc: nc}
+
+	// rgba sets dst to the nonpremultiplied color r, g, b, a.
+	rgba := func(dst []float32, r, g, b, a uint8) {
+		fa := float32(a)
+		if gamma {
+			dst[0] = toLinear[r] * fa
+			dst[1] = toLinear[g] * fa
+			dst[2] = toLinear[b] * fa
+		} else {
+			dst[0] = float32(r) * fa / 255
+			dst[1] = float32(g) * fa / 255
+			dst[2] = float32(b) * fa / 255
+		}
+		dst[3] = fa
+	}
+	// prgba sets dst to the premultiplied color r, g, b, a.
+	prgba := func(dst []float32, r, g, b, a uint8) {
+		if !gamma {
+			dst[0], dst[1], dst[2], dst[3] = float32(r), float32(g), float32(b), float32(a)
+			return
+		}
+		if a == 0 {
+			dst[0], dst[1], dst[2], dst[3] = 0, 0, 0, 0
+			return
+		}
+		un := func(v uint8) uint8 { return uint8((int(v)*255 + int(a)/2) / int(a)) }
+		rgba(dst, un(r), un(g), un(b), a)
+	}
+
+	switch m := m.(type) {
+	case *image.Gray:
+		s.row = func(y int, dst []float32) {
+			i := m.PixOffset(r.Min.X, r.Min.Y+y)
+			for _, v := range m.Pix[i : i+s.w] {
+				switch {
+				case nc == 1 && gamma:
+					dst[0] = toLinear[v] * 255
+				case nc == 1:
+					dst[0] = float32(v)
+				default:
+					rgba(dst, v, v, v, 255)
+				}
+				dst = dst[nc:]
+			}
+		}
+	case *image.RGBA:
+		s.row = func(y int, dst []float32) {
+			i := m.PixOffset(r.Min.X, r.Min.Y+y)
+			pix := m.Pix[i : i+4*s.w]
+			for x := 0; x < s.w; x++ {
+				p := pix[4*x:]
+				prgba(dst[4*x:], p[0], p[1], p[2], p[3])
+			}
+		}
+	case *image.NRGBA:
+		s.row = func(y int, dst []float32) {
+			i := m.PixOffset(r.Min.X, r.Min.Y+y)
+			pix := m.Pix[i : i+4*s.w]
+			for x := 0; x < s.w; x++ {
+				p := pix[4*x:]
+				rgba(dst[4*x:], p[0], p[1], p[2], p[3])
+			}
+		}
+	case *image.YCbCr:
+		s.row = func(y int, dst []float32) {
+			for x := 0; x < s.w; x++ {
+				yi := m.YOffset(r.Min.X+x, r.Min.Y+y)
+				ci := m.COffset(r.Min.X+x, r.Min.Y+y)
+				cr, cg, cb := color.YCbCrToRGB(m.Y[yi], m.Cb[ci], m.Cr[ci])
+				rgba(dst[4*x:], cr, cg, cb, 255)
+			}
+		}
+	default:
+		s.row = func(y int, dst []float32) {
+			for x := 0; x < s.w; x++ {
+				cr, cg, cb, ca := m.At(r.Min.X+x, r.Min.Y+y).RGBA()
+				prgba(dst[4*x:], uint8(cr>>8), uint8(cg>>8), uint8(cb>>8), uint8(ca>>8))
+			}
+		}
+	}
+	return s
+}
+
+// store stores the w×h×nc values in out as 8-bit premultiplied pixels
+// in pix, with rows stride bytes apart.
+func store(pix []byte, stride int, out []float32, w, h, nc int, gamma bool) {
+	for y := 0; y < h; y++ {
+		row := pix[y*stride : y*stride+w*nc]
+		in := out[y*w*nc:]
+		if nc == 1 {
+			for x := range row {
+				if gamma {
+					row[x] = unlinear(in[x] / 255)
+				} else {
+					row[x] = clamp(in[x], 255)
+				}
+			}
+			continue
+		}
+		for x := 0; x < w; x++ {
+			p, v := row[4*x:4*x+4], in[4*x:]
+			a := clamp(v[3], 255)
+			p[3] = a
+			for c := 0; c < 3; c++ {
+				if !gamma {
+					p[c] = clamp(v[c], float32(a))
+				} else if a > 0 {
+					p[c] = uint8((int(unlinear(v[c]/float32(a)))*int(a) + 127) / 255)
+				} else {
+					p[c] = 0
+				}
+			}
+		}
+	}
+}
+
+// clamp returns v rounded to an integer between 0 and max.
+func clamp(v, max float32) uint8 {
+	v += 0.5
+	if v < 0 {
+		return 0
+	}
+	if v > max {
+		return uint8(max)
+	}
+	return uint8(v)
+}
@@ -0,0 +1,173 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package resize scales images.
+//
+// Resize scales any image to an *image.RGBA, and ResizeGray scales
+// an *image.Gray to an *image.Gray. Both read *image.RGBA,
+// *image.NRGBA, *image.Gray, and *image.YCbCr images directly,
+// falling back to the generic At method only for other image types.
+//
+// Scaling works in two passes, first horizontally and then vertically,
+// each splitting its rows among several goroutines. The filter
+// is chosen by Options: the default Box filter averages the source
+// pixels covered by each destination pixel, while Bilinear, Bicubic,
+// and Lanczos3 interpolate, giving smoother and sharper results.
+package resize // import "rsc.io/qr/resize"
+
+import (
+	"image"
+	"runtime"
+	"sync"
+)
+
+// Options controls how an image is resized.
+// A nil *Options means the default options:
+// Box filter, sRGB averaging, and runtime.GOMAXPROCS(0) workers.
+type Options struct {
+	Filter Filter
+
+	// Gamma says to average colors in linear light
+	// instead of in gamma-encoded sRGB values.
+	// Averaging sRGB values darkens fine detail:
+	// a checkerboard of black and white shrinks to 50% gray
+	// instead of the 73% gray that the eye sees.
+	Gamma bool
+
+	// Workers is the maximum number of goroutines to use.
+	// Zero means runtime.GOMAXPROCS(0).
+	Workers int
+}
+
+func (o *Options) get() Options {
+	var opt Options
+	if o != nil {
+		opt = *o
+	}
+	if opt.Workers <= 0 {
+		opt.Workers = runtime.GOMAXPROCS(0)
+	}
+	return opt
+}
+
+// Resize returns a scaled copy of the image slice r of m.
+// The returned image has width w and height h.
+func Resize(m image.Image, r image.Rectangle, w, h int, opt *Options) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	r = r.Intersect(m.Bounds())
+	if w <= 0 || h <= 0 || r.Empty() {
+		return dst
+	}
+	o := opt.get()
+	out := resize(newSource(m, r, 4, o.Gamma), w, h, o)
+	store(dst.Pix, dst.Stride, out, w, h, 4, o.Gamma)
+	return dst
+}
+
+// ResizeGray returns a scaled copy of the gray image slice r of m.
+// The returned image has width w and height h.
+func ResizeGray(m *image.Gray, r image.Rectangle, w, h int, opt *Options) *image.Gray {
+	dst := image.NewGray(image.Rect(0, 0, w, h))
+	r = r.Intersect(m.Bounds())
+	if w <= 0 || h <= 0 || r.Empty() {
+		return dst
+	}
+	o := opt.get()
+	out := resize(newSource(m, r, 1, o.Gamma), w, h, o)
+	store(dst.Pix, dst.Stride, out, w, h, 1, o.Gamma)
+	return dst
+}
+
+// ResizeRGBA returns a scaled copy of the RGBA image slice r of m,
+// using the default options.
+// The returned image has width w and height h.
+func ResizeRGBA(m *image.RGBA, r image.Rectangle, w, h int) *image.RGBA {
+	return Resize(m, r, w, h, nil)
+}
+
+// ResizeNRGBA returns a scaled copy of the NRGBA image slice r of m,
+// using the default options.
+// The returned image has width w and height h.
+func ResizeNRGBA(m *image.NRGBA, r image.Rectangle, w, h int) *image.RGBA {
+	return Resize(m, r, w, h, nil)
+}
+
+// Resample returns a resampled copy of the image slice r of m,
+// using the default options.
+// The returned image has width w and height h.
+func Resample(m image.Image, r image.Rectangle, w, h int) *image.RGBA {
+	return Resize(m, r, w, h, nil)
+}
+
+// resize scales src to w×h, returning the w×h×nc result.
+func resize(src *source, w, h int, o Options) []float32 {
+	nc := src.nc
+	xw := weights(o.Filter, src.w, w)
+	yw := weights(o.Filter, src.h, h)
+
+	// Horizontal pass: each source row becomes a row of width w.
+	tmp := make([]float32, src.h*w*nc)
+	parallel(src.h, o.Workers, func(y0, y1 int) {
+		row := make([]float32, src.w*nc)
+		for y := y0; y < y1; y++ {
+			src.row(y, row)
+			convolve(tmp[y*w*nc:(y+1)*w*nc], row, xw, nc, nc)
+		}
+	})
+
+	// Vertical pass: each column of tmp becomes a column of height h.
+	out := make([]float32, h*w*nc)
+	parallel(h, o.Workers, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			dst := out[y*w*nc : (y+1)*w*nc]
+			for _, t := range yw[y] {
+				src := tmp[t.i*w*nc : (t.i+1)*w*nc]
+				for i, v := range src {
+					dst[i] += t.w * v
+				}
+			}
+		}
+	})
+	return out
+}
+
+// convolve sets each pixel of dst, nc channels wide, to the weighted
+// sum of the pixels of src given by the taps in ws. Source pixels
+// are stride values apart.
+func convolve(dst, src []float32, ws [][]tap, nc, stride int) {
+	for x, taps := range ws {
+		d := dst[x*nc : (x+1)*nc]
+		for i := range d {
+			d[i] = 0
+		}
+		for _, t := range taps {
+			s := src[t.i*stride:]
+			for c := range d {
+				d[c] += t.w * s[c]
+			}
+		}
+	}
+}
+
+// parallel calls f on disjoint ranges covering [0, n),
+// using at most workers goroutines.
+func parallel(n, workers int, f func(lo, hi int)) {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		f(0, n)
+		return
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		lo, hi := n*i/workers, n*(i+1)/workers
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f(lo, hi)
+		}()
+	}
+	wg.Wait()
+}
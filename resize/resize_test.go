@@ -0,0 +1,179 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package resize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+var filters = []Filter{Box, Bilinear, Bicubic, Lanczos3}
+
+// checker returns an n×n black and white checkerboard.
+func checker(n int) *image.Gray {
+	m := image.NewGray(image.Rect(0, 0, n, n))
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if (x+y)%2 == 0 {
+				m.SetGray(x, y, color.Gray{255})
+			}
+		}
+	}
+	return m
+}
+
+// gradient returns a w×h NRGBA image with smoothly varying colors.
+func gradient(w, h int) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			m.SetNRGBA(x, y, color.NRGBA{uint8(255 * x / w), uint8(255 * y / h), 128, 255})
+		}
+	}
+	return m
+}
+
+func TestIdentity(t *testing.T) {
+	src := gradient(17, 11)
+	for _, f := range filters {
+		for _, gamma := range []bool{false, true} {
+			dst := Resize(src, src.Bounds(), 17, 11, &Options{Filter: f, Gamma: gamma})
+			for y := 0; y < 11; y++ {
+				for x := 0; x < 17; x++ {
+					if d := diff(dst.RGBAAt(x, y), src.NRGBAAt(x, y)); d > 1 {
+						t.Fatalf("%v gamma=%v: pixel %d,%d = %v, want %v", f, gamma, x, y, dst.RGBAAt(x, y), src.NRGBAAt(x, y))
+					}
+				}
+			}
+		}
+	}
+}
+
+func diff(c color.RGBA, n color.NRGBA) int {
+	d := 0
+	for _, v := range []int{int(c.R) - int(n.R), int(c.G) - int(n.G), int(c.B) - int(n.B), int(c.A) - int(n.A)} {
+		if v < 0 {
+			v = -v
+		}
+		if v > d {
+			d = v
+		}
+	}
+	return d
+}
+
+func TestChecker(t *testing.T) {
+	src := checker(64)
+	for _, tt := range []struct {
+		gamma bool
+		want  uint8
+	}{
+		{false, 128},
+		{true, 188},
+	} {
+		dst := ResizeGray(src, src.Bounds(), 8, 8, &Options{Gamma: tt.gamma})
+		for i, v := range dst.Pix {
+			if v < tt.want-1 || v > tt.want+1 {
+				t.Fatalf("gamma=%v: pixel %d = %d, want %d", tt.gamma, i, v, tt.want)
+			}
+		}
+	}
+}
+
+func TestUniform(t *testing.T) {
+	c := color.RGBA{40, 80, 120, 200}
+	src := image.NewRGBA(image.Rect(0, 0, 30, 20))
+	for i := 0; i < len(src.Pix); i += 4 {
+		src.Pix[i], src.Pix[i+1], src.Pix[i+2], src.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	for _, f := range filters {
+		for _, size := range []image.Point{{7, 5}, {45, 31}} {
+			dst := Resize(src, src.Bounds(), size.X, size.Y, &Options{Filter: f})
+			for y := 0; y < size.Y; y++ {
+				for x := 0; x < size.X; x++ {
+					if got := dst.RGBAAt(x, y); got != c {
+						t.Fatalf("%v %v: pixel %d,%d = %v, want %v", f, size, x, y, got, c)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestFastPaths checks that the Gray, RGBA, NRGBA, and YCbCr
+// fast paths agree with the generic path.
+func TestFastPaths(t *testing.T) {
+	grad := gradient(40, 30)
+	rgba := image.NewRGBA(grad.Bounds())
+	ycc := image.NewYCbCr(grad.Bounds(), image.YCbCrSubsampleRatio444)
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			c := grad.NRGBAAt(x, y)
+			rgba.Set(x, y, c)
+			yy, cb, cr := color.RGBToYCbCr(c.R, c.G, c.B)
+			ycc.Y[ycc.YOffset(x, y)] = yy
+			ycc.Cb[ycc.COffset(x, y)] = cb
+			ycc.Cr[ycc.COffset(x, y)] = cr
+		}
+	}
+	gray := checker(40)
+
+	for _, f := range filters {
+		opt := &Options{Filter: f}
+		for _, m := range []image.Image{grad, rgba, ycc, gray} {
+			// Hide m's type to force the generic path.
+			generic := Resize(struct{ image.Image }{m}, m.Bounds(), 13, 9, opt)
+			fast := Resize(m, m.Bounds(), 13, 9, opt)
+			for i := range fast.Pix {
+				if d := int(fast.Pix[i]) - int(generic.Pix[i]); d < -1 || d > 1 {
+					t.Fatalf("%v %T: fast path byte %d = %d, generic %d", f, m, i, fast.Pix[i], generic.Pix[i])
+				}
+			}
+		}
+		g := ResizeGray(gray, gray.Bounds(), 13, 9, opt)
+		c := Resize(gray, gray.Bounds(), 13, 9, opt)
+		for i, v := range g.Pix {
+			if v != c.Pix[4*i] {
+				t.Fatalf("%v: ResizeGray byte %d = %d, Resize %d", f, i, v, c.Pix[4*i])
+			}
+		}
+	}
+}
+
+func TestParallel(t *testing.T) {
+	src := gradient(101, 67)
+	for _, f := range filters {
+		one := Resize(src, src.Bounds(), 23, 45, &Options{Filter: f, Workers: 1})
+		many := Resize(src, src.Bounds(), 23, 45, &Options{Filter: f, Workers: 7})
+		if string(one.Pix) != string(many.Pix) {
+			t.Errorf("%v: results differ with 1 and 7 workers", f)
+		}
+	}
+}
+
+func TestSubimage(t *testing.T) {
+	src := gradient(40, 40)
+	r := image.Rect(10, 5, 30, 25)
+	a := Resize(src.SubImage(r), r, 20, 20, nil)
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			if d := diff(a.RGBAAt(x, y), src.NRGBAAt(x+10, y+5)); d > 1 {
+				t.Fatalf("pixel %d,%d = %v, want %v", x, y, a.RGBAAt(x, y), src.NRGBAAt(x+10, y+5))
+			}
+		}
+	}
+}
+
+func BenchmarkResize(b *testing.B) {
+	src := gradient(1024, 768)
+	for _, f := range filters {
+		b.Run(f.String(), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Resize(src, src.Bounds(), 200, 150, &Options{Filter: f})
+			}
+		})
+	}
+}
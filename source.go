@@ -0,0 +1,201 @@
+// Copyright 2011 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qr
+
+// C and Go source output for QR codes, for embedding
+// in firmware that drives small displays.
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// An ArrayLayout specifies how pixels are packed into bytes.
+type ArrayLayout int
+
+const (
+	// RowMajor packs each row into bytes, left to right,
+	// starting each row on a fresh byte.
+	RowMajor ArrayLayout = iota
+
+	// ColumnMajor packs each column into bytes, top to bottom,
+	// starting each column on a fresh byte.
+	ColumnMajor
+
+	// Pages packs the image into horizontal pages 8 pixels tall,
+	// with one byte per column in each page, as used by SSD1306
+	// and similar display controllers. Those controllers expect
+	// the top pixel in the least significant bit (LSBFirst).
+	Pages
+)
+
+var layoutNames = []string{"row-major", "column-major", "pages"}
+
+func (l ArrayLayout) String() string {
+	if RowMajor <= l && l <= Pages {
+		return layoutNames[l]
+	}
+	return fmt.Sprintf("ArrayLayout(%d)", int(l))
+}
+
+// ArrayOptions control the conversion of a code to a byte array.
+type ArrayOptions struct {
+	Name     string      // variable name; default "qr"
+	Package  string      // package name for Go source; default "main"
+	Layout   ArrayLayout // byte packing order
+	LSBFirst bool        // put the first pixel of each byte in the low bit
+	Scale    int         // display pixels per QR pixel; 0 means 1
+	Border   int         // width of quiet zone, in QR pixels
+	Invert   bool        // use 1 bits for white instead of black
+}
+
+func (o *ArrayOptions) name() string {
+	if o == nil || o.Name == "" {
+		return "qr"
+	}
+	return o.Name
+}
+
+// Array returns the code's pixels packed into bytes as
+// described by opt, along with the image width and height
+// in display pixels. A nil opt means row-major, MSB first,
+// one display pixel per QR pixel, with no quiet zone.
+func (c *Code) Array(opt *ArrayOptions) (width, height int, data []byte) {
+	var o ArrayOptions
+	if opt != nil {
+		o = *opt
+	}
+	if o.Scale <= 0 {
+		o.Scale = 1
+	}
+	if o.Border < 0 {
+		o.Border = 0
+	}
+	width = (c.Size + 2*o.Border) * o.Scale
+	height = width
+	black := func(x, y int) bool {
+		return c.Black(x/o.Scale-o.Border, y/o.Scale-o.Border) != o.Invert
+	}
+
+	var out []byte
+	var cur byte
+	n := 0
+	put := func(v bool) {
+		if v {
+			if o.LSBFirst {
+				cur |= 1 << uint(n)
+			} else {
+				cur |= 0x80 >> uint(n)
+			}
+		}
+		if n++; n == 8 {
+			out = append(out, cur)
+			cur, n = 0, 0
+		}
+	}
+	flush := func() {
+		if n > 0 {
+			out = append(out, cur)
+			cur, n = 0, 0
+		}
+	}
+
+	switch o.Layout {
+	case ColumnMajor:
+		for x := 0; x < width; x++ {
+			for y := 0; y < height; y++ {
+				put(black(x, y))
+			}
+			flush()
+		}
+	case Pages:
+		for y0 := 0; y0 < height; y0 += 8 {
+			for x := 0; x < width; x++ {
+				for y := y0; y < y0+8; y++ {
+					put(y < height && black(x, y))
+				}
+			}
+		}
+	default:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				put(black(x, y))
+			}
+			flush()
+		}
+	}
+	return width, height, out
+}
+
+func (o *ArrayOptions) describe(w, h int) string {
+	var layout ArrayLayout
+	order, color := "MSB first", "1 is black"
+	if o != nil {
+		layout = o.Layout
+		if o.LSBFirst {
+			order = "LSB first"
+		}
+		if o.Invert {
+			color = "1 is white"
+		}
+	}
+	return fmt.Sprintf("%dx%d pixels, %v, %s, %s", w, h, layout, order, color)
+}
+
+// writeBytes writes data as a list of hex constants,
+// twelve to a line, each line indented by a tab.
+func writeBytes(b *bytes.Buffer, data []byte) {
+	for i, v := range data {
+		if i%12 == 0 {
+			b.WriteString("\t")
+		}
+		fmt.Fprintf(b, "0x%02x,", v)
+		if i%12 == 11 || i == len(data)-1 {
+			b.WriteString("\n")
+		} else {
+			b.WriteString(" ")
+		}
+	}
+}
+
+// CSource returns a C header defining the code as a uint8_t array
+// laid out as described by opt, along with NAME_WIDTH and NAME_HEIGHT
+// macros giving its size in pixels.
+func (c *Code) CSource(opt *ArrayOptions) []byte {
+	w, h, data := c.Array(opt)
+	name := opt.name()
+	macro := strings.ToUpper(name)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// QR code: %s.\n\n", opt.describe(w, h))
+	fmt.Fprintf(&b, "#include <stdint.h>\n\n")
+	fmt.Fprintf(&b, "#define %s_WIDTH %d\n", macro, w)
+	fmt.Fprintf(&b, "#define %s_HEIGHT %d\n\n", macro, h)
+	fmt.Fprintf(&b, "static const uint8_t %s[%d] = {\n", name, len(data))
+	writeBytes(&b, data)
+	fmt.Fprintf(&b, "};\n")
+	return b.Bytes()
+}
+
+// GoSource returns a Go source file defining the code as a byte slice
+// laid out as described by opt, along with nameWidth and nameHeight
+// constants giving its size in pixels.
+func (c *Code) GoSource(opt *ArrayOptions) []byte {
+	w, h, data := c.Array(opt)
+	name := opt.name()
+	pkg := "main"
+	if opt != nil && opt.Package != "" {
+		pkg = opt.Package
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by rsc.io/qr. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	fmt.Fprintf(&b, "// %s is a QR code: %s.\n", name, opt.describe(w, h))
+	fmt.Fprintf(&b, "var %s = []byte{\n", name)
+	writeBytes(&b, data)
+	fmt.Fprintf(&b, "}\n\n")
+	fmt.Fprintf(&b, "const (\n\t%sWidth  = %d\n\t%sHeight = %d\n)\n", name, w, name, h)
+	return b.Bytes()
+}
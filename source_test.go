@@ -0,0 +1,97 @@
+// Copyright 2011 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qr
+
+import (
+	"bytes"
+	"go/format"
+	"strings"
+	"testing"
+)
+
+func TestArray(t *testing.T) {
+	c, err := Encode("hello, world", L)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, opt := range []ArrayOptions{
+		{},
+		{LSBFirst: true},
+		{Layout: ColumnMajor, Scale: 2},
+		{Layout: Pages, LSBFirst: true, Border: 2},
+		{Layout: Pages, Scale: 3, Invert: true},
+	} {
+		opt := opt
+		w, h, data := c.Array(&opt)
+		scale := opt.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		if want := (c.Size + 2*opt.Border) * scale; w != want || h != want {
+			t.Errorf("%+v: size %dx%d, want %dx%d", opt, w, h, want, want)
+			continue
+		}
+
+		// Locate each pixel in data and compare against the code.
+		nbad := 0
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				var i, bit int
+				switch opt.Layout {
+				case RowMajor:
+					i, bit = y*((w+7)/8)+x/8, x%8
+				case ColumnMajor:
+					i, bit = x*((h+7)/8)+y/8, y%8
+				case Pages:
+					i, bit = y/8*w+x, y%8
+				}
+				if !opt.LSBFirst {
+					bit = 7 - bit
+				}
+				got := data[i]>>uint(bit)&1 != 0
+				want := c.Black(x/scale-opt.Border, y/scale-opt.Border) != opt.Invert
+				if got != want {
+					t.Errorf("%+v: pixel %d,%d = %v, want %v", opt, x, y, got, want)
+					if nbad++; nbad >= 10 {
+						t.Fatalf("too many bad pixels")
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestGoSource(t *testing.T) {
+	c, err := Encode("hello, world", L)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := c.GoSource(&ArrayOptions{Name: "logo", Package: "display", Layout: Pages, LSBFirst: true})
+	fmt, err := format.Source(src)
+	if err != nil {
+		t.Fatalf("GoSource: %v\n%s", err, src)
+	}
+	if !bytes.Equal(fmt, src) {
+		t.Errorf("GoSource is not gofmt'ed:\n%s", src)
+	}
+	for _, s := range []string{"package display\n", "var logo = []byte{\n", "logoWidth  = 21\n"} {
+		if !bytes.Contains(src, []byte(s)) {
+			t.Errorf("GoSource missing %q:\n%s", s, src)
+		}
+	}
+}
+
+func TestCSource(t *testing.T) {
+	c, err := Encode("hello, world", L)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := string(c.CSource(nil))
+	for _, s := range []string{"#define QR_WIDTH 21\n", "static const uint8_t qr[63] = {\n", "};\n"} {
+		if !strings.Contains(src, s) {
+			t.Errorf("CSource missing %q:\n%s", s, src)
+		}
+	}
+}
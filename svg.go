@@ -0,0 +1,36 @@
+// Copyright 2011 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qr
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// SVG returns an SVG image displaying the code.
+// Like PNG, the image includes a 4-pixel white border
+// and uses c.Scale image pixels per QR pixel.
+// The black pixels are drawn as a single path
+// tracing the outlines of the black regions.
+func (c *Code) SVG() []byte {
+	return c.SVGBorder(4)
+}
+
+// SVGBorder is like SVG but surrounds the code with
+// a white border of the given number of QR pixels
+// instead of the usual 4. A negative border is treated as zero.
+func (c *Code) SVGBorder(border int) []byte {
+	if border < 0 {
+		border = 0
+	}
+	n := c.Size + 2*border
+	d := n * c.Scale
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" shape-rendering=\"crispEdges\">\n", d, d, n, n)
+	fmt.Fprintf(&b, "<rect width=\"%d\" height=\"%d\" fill=\"white\"/>\n", n, n)
+	fmt.Fprintf(&b, "<path d=\"%s\" fill=\"black\"/>\n", c.svgPath(border))
+	fmt.Fprintf(&b, "</svg>\n")
+	return b.Bytes()
+}
@@ -0,0 +1,60 @@
+// Copyright 2011 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qr
+
+import (
+	"encoding/xml"
+	"strconv"
+	"testing"
+)
+
+func TestSVG(t *testing.T) {
+	c, err := Encode("hello, world", L)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var svg struct {
+		Width  string `xml:"width,attr"`
+		Height string `xml:"height,attr"`
+		Path   struct {
+			D string `xml:"d,attr"`
+		} `xml:"path"`
+	}
+	if err := xml.Unmarshal(c.SVG(), &svg); err != nil {
+		t.Fatalf("invalid SVG: %v\n%s", err, c.SVG())
+	}
+	d := strconv.Itoa((c.Size + 8) * c.Scale)
+	if svg.Width != d || svg.Height != d {
+		t.Errorf("SVG size = %sx%s, want %sx%s", svg.Width, svg.Height, d, d)
+	}
+	if want := c.svgPath(4); svg.Path.D != want {
+		t.Errorf("SVG path = %q, want %q", svg.Path.D, want)
+	}
+}
+
+func TestSVGBorder(t *testing.T) {
+	c, err := Encode("hello, world", L)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, border := range []int{0, 1, 10} {
+		var svg struct {
+			Width string `xml:"width,attr"`
+			Path  struct {
+				D string `xml:"d,attr"`
+			} `xml:"path"`
+		}
+		data := c.SVGBorder(border)
+		if err := xml.Unmarshal(data, &svg); err != nil {
+			t.Fatalf("border %d: invalid SVG: %v\n%s", border, err, data)
+		}
+		if d := strconv.Itoa((c.Size + 2*border) * c.Scale); svg.Width != d {
+			t.Errorf("border %d: SVG width = %s, want %s", border, svg.Width, d)
+		}
+		if want := c.svgPath(border); svg.Path.D != want {
+			t.Errorf("border %d: SVG path = %q, want %q", border, svg.Path.D, want)
+		}
+	}
+}